@@ -0,0 +1,132 @@
+package flashflood
+
+import (
+	"fmt"
+	"time"
+)
+
+// FlushReason why a batch was released from the buffer
+type FlushReason int
+
+const (
+	// ReasonPolicy released by a custom FlushPolicy
+	ReasonPolicy FlushReason = iota
+	// ReasonGate a gate filled up (GateAmount or GateBytes)
+	ReasonGate
+	// ReasonOverflow the ring overflowed (BufferAmount without a gate)
+	ReasonOverflow
+	// ReasonTimeout the buffer was idle for Timeout
+	ReasonTimeout
+	// ReasonFlushTimeout the last flush was FlushTimeout ago
+	ReasonFlushTimeout
+	// ReasonMaxLatency the oldest element was pushed MaxLatency ago
+	ReasonMaxLatency
+	// ReasonDrain released by Drain
+	ReasonDrain
+	// ReasonGet released by Get
+	ReasonGet
+	// ReasonGetOnChan released by GetOnChan
+	ReasonGetOnChan
+	// ReasonShutdown released by Shutdown
+	ReasonShutdown
+	// ReasonEvict released because its key was evicted (see Keyed)
+	ReasonEvict
+	// ReasonDeadline the elements are due (see Opts.DeadlineOrdered)
+	ReasonDeadline
+	// ReasonFlush released by Flush
+	ReasonFlush
+)
+
+var reasonNames = map[FlushReason]string{
+	ReasonPolicy:       "policy",
+	ReasonGate:         "gate",
+	ReasonOverflow:     "overflow",
+	ReasonTimeout:      "timeout",
+	ReasonFlushTimeout: "flush-timeout",
+	ReasonMaxLatency:   "max-latency",
+	ReasonDrain:        "drain",
+	ReasonGet:          "get",
+	ReasonGetOnChan:    "get-on-chan",
+	ReasonShutdown:     "shutdown",
+	ReasonEvict:        "evict",
+	ReasonDeadline:     "deadline",
+	ReasonFlush:        "flush",
+}
+
+func (r FlushReason) String() string {
+	if name, ok := reasonNames[r]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// BatchInfo metadata of a batch released from the buffer
+type BatchInfo struct {
+	// why the batch was released
+	Reason FlushReason
+	// sequence number of the batch, starting at 1
+	Seq uint64
+	// time the first and last element of the batch were pushed
+	FirstPush time.Time
+	LastPush  time.Time
+	// amount of elements cut from the buffer (before the FuncStack)
+	Size int
+	// accumulated size of the elements according to the sizer (see SetSizer)
+	Bytes int
+}
+
+// Batch the elements of a batch with its metadata, delivered on the channel of GetBatchInfoChan
+type Batch[T any] struct {
+	Items []T
+	Info  BatchInfo
+}
+
+// FuncStackInfo type function to be called as callback on drained elements, with the metadata of their batch
+type FuncStackInfo[T any] func(objs []T, info BatchInfo, ff *FlashFlood[T]) []T
+
+// AddFuncInfo add a "callback" function receiving the batch metadata to the callstack to be performed on the objects drained
+func (i *FlashFlood[T]) AddFuncInfo(f FuncStackInfo[T]) {
+	i.addFunc(func(objs []T, info BatchInfo, ff *FlashFlood[T]) ([]T, error) {
+		return f(objs, info, ff), nil
+	})
+}
+
+// GetBatchInfoChan get the overflow channel delivering every flush as a single Batch with its metadata.
+// Only one of GetChan, GetBatchChan and GetBatchInfoChan can be used per instance
+func (i *FlashFlood[T]) GetBatchInfoChan() (<-chan Batch[T], error) {
+	if i.closed.Load() {
+		return nil, ErrClosed
+	}
+	i.initBatchInfoChan()
+	if !i.setOutput(outputBatchInfo) {
+		return nil, ErrOutputMode
+	}
+	return i.batchInfoChan, nil
+}
+
+func (i *FlashFlood[T]) initBatchInfoChan() {
+	i.batchInfoOnce.Do(func() {
+		i.batchInfoChan = make(chan Batch[T], i.opts.ChannelBuffer)
+	})
+}
+
+// applyFuncs runs objs through the FuncStack, the first failing or panicking function aborts it
+func (i *FlashFlood[T]) applyFuncs(objs []T, info BatchInfo) ([]T, error) {
+	var err error
+	for _, f := range i.funcstack {
+		if objs, err = callFunc(f, objs, info, i); err != nil {
+			return nil, fmt.Errorf("%w (%s batch %d): %w", ErrFuncStack, info.Reason, info.Seq, err)
+		}
+	}
+	return objs, nil
+}
+
+// callFunc calls f and turns a panic into an error wrapping ErrPanic
+func callFunc[T any](f stackFunc[T], objs []T, info BatchInfo, ff *FlashFlood[T]) (out []T, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			out, err = nil, fmt.Errorf("%w: %v", ErrPanic, r)
+		}
+	}()
+	return f(objs, info, ff)
+}
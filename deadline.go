@@ -0,0 +1,297 @@
+package flashflood
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// deadlineNode an element in the heap of a deadlineQueue
+type deadlineNode[T any] struct {
+	e entry[T]
+	// unix nano time the element is due
+	deadline int64
+	// keeps elements with the same deadline in push order
+	seq int64
+}
+
+// deadlineQueue a min-heap releasing the element with the earliest deadline first (EDF)
+type deadlineQueue[T any] struct {
+	nodes    []deadlineNode[T]
+	deadline func(obj T) time.Time
+	// deadline of elements without one: their push time plus timeout
+	timeout time.Duration
+	// sequence of the next pushed element and of the next unshifted element
+	back  int64
+	front int64
+
+	// the order computed by at so far (indexes in nodes) and the candidates for the next element, reset on every
+	// modification
+	order []int
+	cand  []int
+
+	// oldest push time, valid unless stale
+	oldestPush int64
+	stale      bool
+}
+
+func newDeadlineQueue[T any](opts *Opts, capacity int) *deadlineQueue[T] {
+	return &deadlineQueue[T]{
+		nodes:   make([]deadlineNode[T], 0, capacity),
+		timeout: opts.Timeout,
+		front:   -1,
+		stale:   true,
+	}
+}
+
+func (q *deadlineQueue[T]) len() int {
+	return len(q.nodes)
+}
+
+func (q *deadlineQueue[T]) less(a, b int) bool {
+	if q.nodes[a].deadline != q.nodes[b].deadline {
+		return q.nodes[a].deadline < q.nodes[b].deadline
+	}
+	return q.nodes[a].seq < q.nodes[b].seq
+}
+
+// pushBack adds e with the deadline of the deadline function, or its push time plus Timeout
+func (q *deadlineQueue[T]) pushBack(e entry[T]) {
+	q.pushDeadline(e, 0)
+}
+
+// pushDeadline adds e due at deadline (unix nano), 0 is the default deadline of pushBack
+func (q *deadlineQueue[T]) pushDeadline(e entry[T], deadline int64) {
+	if deadline == 0 {
+		deadline = q.defaultDeadline(e)
+	}
+	q.push(deadlineNode[T]{e: e, deadline: deadline, seq: q.back})
+	q.back++
+}
+
+func (q *deadlineQueue[T]) defaultDeadline(e entry[T]) int64 {
+	if q.deadline != nil {
+		return q.deadline(e.value).UnixNano()
+	}
+	return e.pushed + int64(q.timeout)
+}
+
+// pushFront adds e with the earliest deadline in the queue, it leaves before the elements due at the same time
+func (q *deadlineQueue[T]) pushFront(e entry[T]) {
+	deadline := q.defaultDeadline(e)
+	if len(q.nodes) > 0 && q.nodes[0].deadline < deadline {
+		deadline = q.nodes[0].deadline
+	}
+	q.push(deadlineNode[T]{e: e, deadline: deadline, seq: q.front})
+	q.front--
+}
+
+func (q *deadlineQueue[T]) push(n deadlineNode[T]) {
+	switch {
+	case len(q.nodes) == 0:
+		q.oldestPush, q.stale = n.e.pushed, false
+	case !q.stale && n.e.pushed < q.oldestPush:
+		q.oldestPush = n.e.pushed
+	}
+
+	q.nodes = append(q.nodes, n)
+	for k := len(q.nodes) - 1; k > 0; {
+		parent := (k - 1) / 2
+		if !q.less(k, parent) {
+			break
+		}
+		q.nodes[k], q.nodes[parent] = q.nodes[parent], q.nodes[k]
+		k = parent
+	}
+	q.modified()
+}
+
+func (q *deadlineQueue[T]) popFront() entry[T] {
+	var zero deadlineNode[T]
+	e := q.nodes[0].e
+	last := len(q.nodes) - 1
+	q.nodes[0] = q.nodes[last]
+	// release the reference for the garbage collector
+	q.nodes[last] = zero
+	q.nodes = q.nodes[:last]
+	q.down(0)
+
+	if e.pushed == q.oldestPush {
+		q.stale = true
+	}
+	q.modified()
+	return e
+}
+
+func (q *deadlineQueue[T]) down(k int) {
+	for {
+		child := 2*k + 1
+		if child >= len(q.nodes) {
+			return
+		}
+		if right := child + 1; right < len(q.nodes) && q.less(right, child) {
+			child = right
+		}
+		if !q.less(child, k) {
+			return
+		}
+		q.nodes[k], q.nodes[child] = q.nodes[child], q.nodes[k]
+		k = child
+	}
+}
+
+// at returns the k-th element to leave the queue
+func (q *deadlineQueue[T]) at(k int) *entry[T] {
+	return &q.nodes[q.nodeAt(k)].e
+}
+
+// nodeAt returns the index of the k-th node to leave the queue. The heap is walked from the root with a heap of
+// candidates, consecutive calls without modification continue where the previous one stopped
+func (q *deadlineQueue[T]) nodeAt(k int) int {
+	if len(q.order) == 0 && len(q.cand) == 0 {
+		q.cand = append(q.cand, 0)
+	}
+	for len(q.order) <= k {
+		next := q.cand[0]
+		q.popCandidate()
+		q.order = append(q.order, next)
+		for _, child := range [2]int{2*next + 1, 2*next + 2} {
+			if child < len(q.nodes) {
+				q.pushCandidate(child)
+			}
+		}
+	}
+	return q.order[k]
+}
+
+func (q *deadlineQueue[T]) pushCandidate(n int) {
+	q.cand = append(q.cand, n)
+	for k := len(q.cand) - 1; k > 0; {
+		parent := (k - 1) / 2
+		if !q.less(q.cand[k], q.cand[parent]) {
+			break
+		}
+		q.cand[k], q.cand[parent] = q.cand[parent], q.cand[k]
+		k = parent
+	}
+}
+
+func (q *deadlineQueue[T]) popCandidate() {
+	last := len(q.cand) - 1
+	q.cand[0] = q.cand[last]
+	q.cand = q.cand[:last]
+	for k := 0; ; {
+		child := 2*k + 1
+		if child >= len(q.cand) {
+			return
+		}
+		if right := child + 1; right < len(q.cand) && q.less(q.cand[right], q.cand[child]) {
+			child = right
+		}
+		if !q.less(q.cand[child], q.cand[k]) {
+			return
+		}
+		q.cand[k], q.cand[child] = q.cand[child], q.cand[k]
+		k = child
+	}
+}
+
+// oldest returns the oldest push time, it is only searched again after the oldest element left
+func (q *deadlineQueue[T]) oldest() int64 {
+	if q.stale {
+		q.oldestPush = math.MaxInt64
+		for k := range q.nodes {
+			q.oldestPush = min(q.oldestPush, q.nodes[k].e.pushed)
+		}
+		q.stale = false
+	}
+	return q.oldestPush
+}
+
+// due returns the amount of elements due before limit (unix nano) and the earliest deadline
+func (q *deadlineQueue[T]) due(limit int64) (int, int64) {
+	if len(q.nodes) == 0 {
+		return 0, 0
+	}
+	n := 0
+	for n < len(q.nodes) && q.nodes[q.nodeAt(n)].deadline <= limit {
+		n++
+	}
+	return n, q.nodes[0].deadline
+}
+
+func (q *deadlineQueue[T]) reset() {
+	clear(q.nodes)
+	q.nodes = q.nodes[:0]
+	q.stale = true
+	q.modified()
+}
+
+func (q *deadlineQueue[T]) filter(keep func(e *entry[T]) bool) {
+	var zero deadlineNode[T]
+	n := 0
+	for k := range q.nodes {
+		if keep(&q.nodes[k].e) {
+			q.nodes[n] = q.nodes[k]
+			n++
+		}
+	}
+	for k := n; k < len(q.nodes); k++ {
+		q.nodes[k] = zero
+	}
+	q.nodes = q.nodes[:n]
+	for k := n/2 - 1; k >= 0; k-- {
+		q.down(k)
+	}
+	q.stale = true
+	q.modified()
+}
+
+// modified drops the order computed by at
+func (q *deadlineQueue[T]) modified() {
+	q.order = q.order[:0]
+	q.cand = q.cand[:0]
+}
+
+// DeadlinePolicy releases the elements due within Opts.DeadlineSlack of their deadline, regardless of the gate (requires
+// Opts.DeadlineOrdered)
+type DeadlinePolicy struct{}
+
+// Release returns the elements that are due
+func (p DeadlinePolicy) Release(s BufferStats) (int, FlushReason) {
+	return s.Due, ReasonDeadline
+}
+
+// Wait returns the time until the next element is due
+func (p DeadlinePolicy) Wait(s BufferStats) (time.Duration, bool) {
+	if s.Due > 0 {
+		return 0, true
+	}
+	return s.NextDue, s.NextDue > 0
+}
+
+// SetDeadlineFunc sets the function returning the deadline of an element with Opts.DeadlineOrdered, without it elements
+// are due Timeout after they were pushed. Set it before pushing
+func (i *FlashFlood[T]) SetDeadlineFunc(f func(obj T) time.Time) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	if q, ok := i.buffer.(*deadlineQueue[T]); ok {
+		q.deadline = f
+	}
+}
+
+// PushDeadline add objects to buffer due at deadline, with Opts.DeadlineOrdered they leave the buffer earliest deadline
+// first and are flushed once due within DeadlineSlack. Without DeadlineOrdered the deadline is ignored
+func (i *FlashFlood[T]) PushDeadline(deadline time.Time, objs ...T) error {
+	return i.PushDeadlineContext(context.Background(), deadline, objs...)
+}
+
+// PushDeadlineContext add objects to buffer due at deadline, blocks until there is room below MaxBufferAmount or ctx
+// is done
+func (i *FlashFlood[T]) PushDeadlineContext(ctx context.Context, deadline time.Time, objs ...T) error {
+	var p placement
+	if !deadline.IsZero() {
+		p.deadline = deadline.UnixNano()
+	}
+	return i.pushContext(ctx, p, objs)
+}
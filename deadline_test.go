@@ -0,0 +1,46 @@
+package flashflood
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// TestDeadlinePeekOrder at must predict the order popFront releases the elements in
+func TestDeadlinePeekOrder(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	q := newDeadlineQueue[int](handleOpts(&Opts{}), 0)
+
+	for round := 0; round < 200; round++ {
+		for n := rnd.Intn(10); n > 0; n-- {
+			v := rnd.Intn(100)
+			if rnd.Intn(5) == 0 {
+				q.pushFront(entry[int]{value: v, pushed: int64(round)})
+			} else {
+				// few distinct deadlines, so ties are broken by push order
+				q.pushDeadline(entry[int]{value: v, pushed: int64(round)}, int64(1+rnd.Intn(5)))
+			}
+		}
+
+		peeked := make([]int, q.len())
+		for k := range peeked {
+			peeked[k] = q.at(k).value
+		}
+		pops := rnd.Intn(q.len() + 1)
+		for k := 0; k < pops; k++ {
+			if v := q.popFront().value; v != peeked[k] {
+				t.Fatalf("expected: %d at %d; got %d", peeked[k], k, v)
+			}
+		}
+		if q.len() > 0 && q.oldest() != q.oldestScan() {
+			t.Fatalf("expected: oldest %d; got %d", q.oldestScan(), q.oldest())
+		}
+	}
+}
+
+func (q *deadlineQueue[T]) oldestScan() int64 {
+	oldest := q.nodes[0].e.pushed
+	for _, n := range q.nodes {
+		oldest = min(oldest, n.e.pushed)
+	}
+	return oldest
+}
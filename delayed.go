@@ -0,0 +1,141 @@
+package flashflood
+
+import (
+	"context"
+	"time"
+)
+
+// delayedEntry an element held back until it is due (see PushAt)
+type delayedEntry[T any] struct {
+	value T
+	// unix nano time the element moves into the buffer
+	at int64
+	// keeps elements due at the same time in push order
+	seq uint64
+}
+
+// delayedHeap min-heap of the delayed elements, the earliest due first
+type delayedHeap[T any] struct {
+	items []delayedEntry[T]
+	seq   uint64
+}
+
+func (h *delayedHeap[T]) len() int {
+	return len(h.items)
+}
+
+func (h *delayedHeap[T]) less(a, b int) bool {
+	if h.items[a].at != h.items[b].at {
+		return h.items[a].at < h.items[b].at
+	}
+	return h.items[a].seq < h.items[b].seq
+}
+
+// next returns the unix nano time the earliest element is due, the heap must not be empty
+func (h *delayedHeap[T]) next() int64 {
+	return h.items[0].at
+}
+
+func (h *delayedHeap[T]) push(value T, at int64) {
+	h.items = append(h.items, delayedEntry[T]{value: value, at: at, seq: h.seq})
+	h.seq++
+	for k := len(h.items) - 1; k > 0; {
+		parent := (k - 1) / 2
+		if !h.less(k, parent) {
+			break
+		}
+		h.items[k], h.items[parent] = h.items[parent], h.items[k]
+		k = parent
+	}
+}
+
+// filter removes the elements keep returns false for
+func (h *delayedHeap[T]) filter(keep func(obj *T) bool) {
+	var zero delayedEntry[T]
+	n := 0
+	for k := range h.items {
+		if keep(&h.items[k].value) {
+			h.items[n] = h.items[k]
+			n++
+		}
+	}
+	for k := n; k < len(h.items); k++ {
+		h.items[k] = zero
+	}
+	h.items = h.items[:n]
+	for k := n/2 - 1; k >= 0; k-- {
+		h.down(k)
+	}
+}
+
+func (h *delayedHeap[T]) pop() T {
+	var zero delayedEntry[T]
+	value := h.items[0].value
+	last := len(h.items) - 1
+	h.items[0] = h.items[last]
+	// release the reference for the garbage collector
+	h.items[last] = zero
+	h.items = h.items[:last]
+	h.down(0)
+	return value
+}
+
+func (h *delayedHeap[T]) down(k int) {
+	for {
+		child := 2*k + 1
+		if child >= len(h.items) {
+			return
+		}
+		if right := child + 1; right < len(h.items) && h.less(right, child) {
+			child = right
+		}
+		if !h.less(child, k) {
+			return
+		}
+		h.items[k], h.items[child] = h.items[child], h.items[k]
+		k = child
+	}
+}
+
+func (h *delayedHeap[T]) reset() {
+	clear(h.items)
+	h.items = h.items[:0]
+}
+
+// PushAt add objects to buffer at t, until then they are held back and not flushed. Once due they are pushed, so gates
+// and timeouts apply from then on. Blocks while the buffer (including the held back elements) is at MaxBufferAmount
+func (i *FlashFlood[T]) PushAt(t time.Time, objs ...T) error {
+	return i.PushAtContext(context.Background(), t, objs...)
+}
+
+// PushAtContext add objects to buffer at t, blocks until there is room below MaxBufferAmount or ctx is done
+func (i *FlashFlood[T]) PushAtContext(ctx context.Context, t time.Time, objs ...T) error {
+	return i.pushContext(ctx, placement{at: t.UnixNano()}, objs)
+}
+
+// PushAfter add objects to buffer after d, see PushAt
+func (i *FlashFlood[T]) PushAfter(d time.Duration, objs ...T) error {
+	return i.PushAt(time.Now().Add(d), objs...)
+}
+
+// delay holds objs back until at, make sure we have a mutex Lock
+func (i *FlashFlood[T]) delay(objs []T, at int64) {
+	earliest := i.delayed.len() == 0 || at < i.delayed.next()
+	for _, obj := range objs {
+		i.delayed.push(obj, at)
+	}
+	if earliest {
+		i.wakeTimer()
+	}
+}
+
+// promote pushes the held back elements due at now, make sure we have a mutex Lock
+func (i *FlashFlood[T]) promote(now time.Time) {
+	var due []T
+	for i.delayed.len() > 0 && i.delayed.next() <= now.UnixNano() {
+		due = append(due, i.delayed.pop())
+	}
+	if len(due) > 0 {
+		i.pushLocked(due, placement{})
+	}
+}
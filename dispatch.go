@@ -0,0 +1,159 @@
+package flashflood
+
+import (
+	"context"
+	"errors"
+)
+
+// queuedBatch a batch cut from the buffer waiting for the dispatcher
+type queuedBatch[T any] struct {
+	objs []T
+	info BatchInfo
+}
+
+// dispatcher runs the FuncStack and the channel sends of the batches cut from the buffer outside the lock, in the
+// order they were cut (see Opts.AsyncDispatch)
+type dispatcher[T any] struct {
+	queue []queuedBatch[T]
+	// amount of elements in the queue, they count towards MaxBufferAmount
+	queued int
+	// amount of batches handed to and done by the dispatcher, see Flush
+	enqueued  uint64
+	delivered uint64
+
+	ready  chan struct{}
+	stop   chan struct{}
+	done   chan struct{}
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func newDispatcher[T any]() *dispatcher[T] {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &dispatcher[T]{
+		ready:  make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// enqueue hands a batch to the dispatcher, make sure we have a mutex Lock
+func (i *FlashFlood[T]) enqueue(objs []T, info BatchInfo) {
+	d := i.dispatcher
+	d.queue = append(d.queue, queuedBatch[T]{objs: objs, info: info})
+	d.queued += len(objs)
+	d.enqueued++
+
+	select {
+	case d.ready <- struct{}{}:
+	default:
+	}
+}
+
+// handleDispatch delivers the queued batches until the dispatcher is stopped (after the queue is empty) or canceled
+func handleDispatch[T any](i *FlashFlood[T]) {
+	d := i.dispatcher
+	defer close(d.done)
+
+	for {
+		i.mutex.Lock()
+		if len(d.queue) == 0 {
+			i.mutex.Unlock()
+			select {
+			case <-d.ready:
+				continue
+			case <-d.stop:
+				// the last batches may have been queued right before stop
+				if i.dispatchPending() {
+					continue
+				}
+				return
+			case <-d.ctx.Done():
+				return
+			}
+		}
+		b := d.queue[0]
+		d.queue[0] = queuedBatch[T]{}
+		d.queue = d.queue[1:]
+		i.mutex.Unlock()
+
+		err := i.deliver(d.ctx, b.objs, b.info)
+
+		i.mutex.Lock()
+		if errors.Is(err, ErrFuncStack) {
+			// the batches queued behind a retained batch go back into the buffer after it, keeping their order
+			if i.retains(err) {
+				i.requeue()
+			}
+			i.failBatch(b.objs, b.info, err)
+		}
+		d.queued -= len(b.objs)
+		d.delivered++
+		i.signalSpace()
+		if err != nil && d.ctx.Err() == nil {
+			i.reportError(err)
+		}
+		i.unlock()
+
+		if d.ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// requeue puts the queued batches back at the front of the buffer in the order they were cut, they are released
+// again once the retained batch is retried. Make sure we have a mutex Lock
+func (i *FlashFlood[T]) requeue() {
+	d := i.dispatcher
+	for k := len(d.queue) - 1; k >= 0; k-- {
+		i.retain(d.queue[k].objs, d.queue[k].info)
+		d.queued -= len(d.queue[k].objs)
+		d.delivered++
+	}
+	clear(d.queue)
+	d.queue = d.queue[:0]
+}
+
+func (i *FlashFlood[T]) dispatchPending() bool {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	return len(i.dispatcher.queue) > 0
+}
+
+// stopDispatch waits until the dispatcher delivered the queued batches, when ctx is done first the rest is dropped
+func (i *FlashFlood[T]) stopDispatch(ctx context.Context) error {
+	if i.dispatcher == nil {
+		return nil
+	}
+
+	d := i.dispatcher
+	close(d.stop)
+	select {
+	case <-d.done:
+		return nil
+	case <-ctx.Done():
+		d.cancel()
+		<-d.done
+		return ctx.Err()
+	}
+}
+
+// haltDispatch stops the dispatcher right away, the queued batches are dropped
+func (i *FlashFlood[T]) haltDispatch() {
+	if i.dispatcher == nil {
+		return
+	}
+
+	i.dispatcher.cancel()
+	<-i.dispatcher.done
+}
+
+// queued returns the amount of elements waiting for the dispatcher, make sure we have a mutex Lock
+func (i *FlashFlood[T]) queued() int {
+	if i.dispatcher == nil {
+		return 0
+	}
+	return i.dispatcher.queued
+}
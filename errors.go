@@ -0,0 +1,23 @@
+package flashflood
+
+import "errors"
+
+var (
+	// ErrClosed is returned when the instance is used after Close or Shutdown
+	ErrClosed = errors.New("flashflood: instance is closed")
+	// ErrFuncStack wraps the error returned by a FuncStackE function
+	ErrFuncStack = errors.New("flashflood: funcstack failed")
+	// ErrPanic wraps a panic recovered from a FuncStack function
+	ErrPanic = errors.New("flashflood: funcstack panicked")
+	// ErrFull is returned when elements do not fit in the buffer below Opts.MaxBufferAmount
+	ErrFull = errors.New("flashflood: buffer is full")
+	// ErrMaxKeys is returned by Keyed when an element with a new key is pushed while Opts.MaxKeys keys are buffering
+	ErrMaxKeys = errors.New("flashflood: maximum amount of keys reached")
+	// ErrPriority is returned by PushPriority for a level outside Opts.PriorityLevels
+	ErrPriority = errors.New("flashflood: priority level out of range")
+	// ErrNotFetched is returned by Shutdown when the remaining buffer could not be flushed (and is dropped) because no
+	// channel was fetched, and by Flush as there is no consumer to wait for
+	ErrNotFetched = errors.New("flashflood: channel not fetched")
+	// ErrOutputMode is returned when both the element channel and the batch channel are requested from one instance
+	ErrOutputMode = errors.New("flashflood: element and batch channel can not be used together")
+)
@@ -0,0 +1,108 @@
+package flashflood
+
+import (
+	"errors"
+	"time"
+)
+
+// FailurePolicy determines what happens with a batch when a FuncStackE function fails
+type FailurePolicy int
+
+const (
+	// FailureRetain puts the batch back at the front of the buffer, it is retried after Timeout (default)
+	FailureRetain FailurePolicy = iota
+	// FailureDrop discards the batch
+	FailureDrop
+	// FailureDeadLetter hands the batch to the OnDeadLetter callback
+	FailureDeadLetter
+)
+
+// FuncStackE type function to be called as callback on drained elements, returning an error fails the batch (see
+// Opts.FailurePolicy)
+type FuncStackE[T any] func(objs []T, ff *FlashFlood[T]) ([]T, error)
+
+// AddFuncE add a "callback" function that can fail to the callstack to be performed on the objects drained
+func (i *FlashFlood[T]) AddFuncE(f FuncStackE[T]) {
+	i.addFunc(func(objs []T, _ BatchInfo, ff *FlashFlood[T]) ([]T, error) {
+		return f(objs, ff)
+	})
+}
+
+// OnDeadLetter sets the callback receiving the batches a FuncStackE function failed on, used by FailureDeadLetter, for
+// batches a FuncStack function panicked on and for batches that can no longer be retained after Close or Shutdown. f
+// is called while the buffer is locked, so it must not call methods of the instance
+func (i *FlashFlood[T]) OnDeadLetter(f func(objs []T, info BatchInfo, err error)) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	i.onDeadLetter = f
+}
+
+// Errors returns the channel receiving the errors of flushes not triggered by a method call (timeouts and ring
+// releases), methods return their errors directly. Errors are discarded while the channel is full, the channel is
+// closed by Close and Shutdown
+func (i *FlashFlood[T]) Errors() <-chan error {
+	return i.errs
+}
+
+// reportError hands an asynchronous error to the Errors channel and to Opts.OnError once the lock is released (see
+// unlock), make sure we have a mutex Lock
+func (i *FlashFlood[T]) reportError(err error) {
+	if i.opts.OnError != nil {
+		i.reported = append(i.reported, err)
+	}
+	select {
+	case i.errs <- err:
+	default:
+	}
+}
+
+// unlock releases the mutex and calls Opts.OnError with the errors reported while it was held, so OnError may call
+// methods of the instance
+func (i *FlashFlood[T]) unlock() {
+	reported := i.reported
+	i.reported = nil
+	i.mutex.Unlock()
+
+	for _, err := range reported {
+		i.opts.OnError(err)
+	}
+}
+
+// failBatch handles a batch the FuncStack failed on according to the FailurePolicy, a batch that caused a panic is
+// never retained but quarantined to OnDeadLetter. Make sure we have a mutex Lock
+func (i *FlashFlood[T]) failBatch(objs []T, info BatchInfo, err error) {
+	i.stats.failed.Add(1)
+	panicked := errors.Is(err, ErrPanic)
+	if panicked {
+		i.stats.panics.Add(1)
+	}
+
+	switch {
+	case panicked:
+		if i.onDeadLetter != nil {
+			i.onDeadLetter(objs, info, err)
+		}
+	case i.retains(err):
+		i.retain(objs, info)
+	case i.opts.FailurePolicy == FailureDrop:
+	case i.onDeadLetter != nil:
+		i.onDeadLetter(objs, info, err)
+	}
+}
+
+// retains reports if failBatch puts a batch failed with err back in the buffer, make sure we have a mutex Lock
+func (i *FlashFlood[T]) retains(err error) bool {
+	return i.opts.FailurePolicy == FailureRetain && !i.closed.Load() && !errors.Is(err, ErrPanic)
+}
+
+// retain puts a failed batch back at the front of the buffer and postpones its retry by Timeout, make sure we have a
+// mutex Lock
+func (i *FlashFlood[T]) retain(objs []T, info BatchInfo) {
+	i.armTimer()
+	// the batch was cut from the front, so its first push keeps the front of the buffer the oldest element
+	pushed := info.FirstPush.UnixNano()
+	for k := len(objs) - 1; k >= 0; k-- {
+		i.buffer.pushFront(i.newEntry(objs[k], pushed))
+	}
+	i.retryAt = time.Now().Add(i.timeout).UnixNano()
+}
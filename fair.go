@@ -0,0 +1,270 @@
+package flashflood
+
+import "math"
+
+// Fairness determines the order elements of different keys leave the buffer in (see SetFairnessKey)
+type Fairness int
+
+const (
+	// FairnessNone elements leave in the order they were pushed (default)
+	FairnessNone Fairness = iota
+	// FairnessRoundRobin keys take turns, every turn releases one element of a key
+	FairnessRoundRobin
+	// FairnessWeighted keys take turns (deficit round-robin), every turn releases as many elements of a key as its
+	// weight (see SetFairnessWeight)
+	FairnessWeighted
+)
+
+// lane the elements of a single key in a fairQueue
+type lane[T any] struct {
+	key   string
+	items ring[entry[T]]
+	// elements the lane may still release in its current turn
+	deficit int
+
+	// elements of the lane in the order computed by at, valid while peekGen matches the queue
+	peeked  int
+	peekGen uint64
+}
+
+// fairQueue a queue with a lane per key, the lanes holding elements take turns. Within a lane elements keep their
+// order
+type fairQueue[T any] struct {
+	mode   Fairness
+	key    func(obj T) string
+	weight func(key string) int
+
+	lanes map[string]*lane[T]
+	// the lanes holding elements, the front lane has the turn
+	active ring[*lane[T]]
+	count  int
+
+	// the order computed by at so far, reset on every modification
+	order  []*entry[T]
+	gen    uint64
+	pos    int
+	credit int
+}
+
+func newFairQueue[T any](mode Fairness) *fairQueue[T] {
+	return &fairQueue[T]{mode: mode, lanes: map[string]*lane[T]{}}
+}
+
+func (q *fairQueue[T]) keyOf(obj T) string {
+	if q.key == nil {
+		return ""
+	}
+	return q.key(obj)
+}
+
+// quantum returns the amount of elements key releases per turn
+func (q *fairQueue[T]) quantum(key string) int {
+	if q.mode != FairnessWeighted || q.weight == nil {
+		return 1
+	}
+	if w := q.weight(key); w > 1 {
+		return w
+	}
+	return 1
+}
+
+func (q *fairQueue[T]) len() int {
+	return q.count
+}
+
+// lane returns the lane of key, a new lane is added to the active lanes at the back or the front
+func (q *fairQueue[T]) lane(key string, front bool) *lane[T] {
+	if l, ok := q.lanes[key]; ok {
+		return l
+	}
+	l := &lane[T]{key: key}
+	q.lanes[key] = l
+	if front {
+		q.active.pushFront(l)
+	} else {
+		q.active.pushBack(l)
+	}
+	return l
+}
+
+func (q *fairQueue[T]) pushBack(e entry[T]) {
+	q.lane(q.keyOf(e.value), false).items.pushBack(e)
+	q.count++
+	q.modified()
+}
+
+// pushFront adds e to the front of its lane, a key without elements gets the next turn
+func (q *fairQueue[T]) pushFront(e entry[T]) {
+	q.lane(q.keyOf(e.value), true).items.pushFront(e)
+	q.count++
+	q.modified()
+}
+
+// popFront releases an element of the lane having the turn, the lane passes the turn once it used its quantum
+func (q *fairQueue[T]) popFront() entry[T] {
+	l := *q.active.at(0)
+	if l.deficit == 0 {
+		l.deficit = q.quantum(l.key)
+	}
+	e := l.items.popFront()
+	l.deficit--
+	q.count--
+
+	switch {
+	case l.items.len() == 0:
+		// idle keys do not keep a lane
+		q.active.popFront()
+		delete(q.lanes, l.key)
+	case l.deficit == 0:
+		q.active.popFront()
+		q.active.pushBack(l)
+	}
+	q.modified()
+	return e
+}
+
+// at returns the k-th element to leave the queue by playing the turns ahead. Consecutive calls without modification
+// continue where the previous one stopped, so peeking a batch is linear
+func (q *fairQueue[T]) at(k int) *entry[T] {
+	for len(q.order) <= k {
+		l := *q.active.at(q.pos % q.active.len())
+		if l.peekGen != q.gen {
+			l.peekGen = q.gen
+			l.peeked = 0
+		}
+		if l.peeked == l.items.len() {
+			q.pos++
+			q.credit = 0
+			continue
+		}
+		if q.credit == 0 {
+			q.credit = q.quantum(l.key)
+			// the first turn of a lane continues the turn it was in
+			if q.pos < q.active.len() && l.deficit > 0 {
+				q.credit = l.deficit
+			}
+		}
+
+		q.order = append(q.order, l.items.at(l.peeked))
+		l.peeked++
+		q.credit--
+		if q.credit == 0 {
+			q.pos++
+		}
+	}
+	return q.order[k]
+}
+
+// oldest returns the oldest front of the lanes, the front of a lane holds its oldest element
+func (q *fairQueue[T]) oldest() int64 {
+	oldest := int64(math.MaxInt64)
+	for k := 0; k < q.active.len(); k++ {
+		if pushed := (*q.active.at(k)).items.at(0).pushed; pushed < oldest {
+			oldest = pushed
+		}
+	}
+	return oldest
+}
+
+func (q *fairQueue[T]) reset() {
+	clear(q.lanes)
+	q.active.reset()
+	q.count = 0
+	q.modified()
+}
+
+func (q *fairQueue[T]) filter(keep func(e *entry[T]) bool) {
+	q.count = 0
+	q.active.filter(func(l **lane[T]) bool {
+		(*l).items.filter(keep)
+		q.count += (*l).items.len()
+		if (*l).items.len() == 0 {
+			delete(q.lanes, (*l).key)
+			return false
+		}
+		return true
+	})
+	q.modified()
+}
+
+// modified drops the order computed by at
+func (q *fairQueue[T]) modified() {
+	q.order = q.order[:0]
+	q.gen++
+	q.pos = 0
+	q.credit = 0
+}
+
+// keyLen returns the amount of elements of key
+func (q *fairQueue[T]) keyLen(key string) int {
+	if l, ok := q.lanes[key]; ok {
+		return l.items.len()
+	}
+	return 0
+}
+
+// fairQueues returns the queues of the priority levels with Fairness, make sure we have a mutex Lock
+func (i *FlashFlood[T]) fairQueues() []*fairQueue[T] {
+	switch q := i.buffer.(type) {
+	case *fairQueue[T]:
+		return []*fairQueue[T]{q}
+	case *priorityQueue[T]:
+		var fair []*fairQueue[T]
+		for _, level := range q.levels {
+			if fq, ok := level.(*fairQueue[T]); ok {
+				fair = append(fair, fq)
+			}
+		}
+		return fair
+	}
+	return nil
+}
+
+// SetFairnessKey sets the function returning the key elements are interleaved by, requires Opts.Fairness. Elements
+// with the same key keep their order. Set it before pushing
+func (i *FlashFlood[T]) SetFairnessKey(f func(obj T) string) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	for _, q := range i.fairQueues() {
+		q.key = f
+	}
+}
+
+// SetFairnessWeight sets the function returning the weight of a key with FairnessWeighted, a key with weight 3 releases
+// 3 elements per turn. Weights below 1 count as 1
+func (i *FlashFlood[T]) SetFairnessWeight(f func(key string) int) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	for _, q := range i.fairQueues() {
+		q.weight = f
+	}
+}
+
+// withinQuota reports if objs fit below Opts.KeyQuota, with buffered the elements in the buffer (of all priority
+// levels) count as well. Make sure we have a mutex Lock
+func (i *FlashFlood[T]) withinQuota(objs []T, buffered bool) bool {
+	if i.opts.KeyQuota == 0 {
+		return true
+	}
+	fair := i.fairQueues()
+	if len(fair) == 0 {
+		return true
+	}
+
+	counts := make(map[string]int64, 1)
+	for _, obj := range objs {
+		key := fair[0].keyOf(obj)
+		n, ok := counts[key]
+		if !ok && buffered {
+			for _, q := range fair {
+				n += int64(q.keyLen(key))
+			}
+		}
+		n++
+		if n > i.opts.KeyQuota {
+			return false
+		}
+		counts[key] = n
+	}
+	return true
+}
@@ -0,0 +1,36 @@
+package flashflood
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+// TestFairPeekOrder at must predict the order popFront releases the elements in
+func TestFairPeekOrder(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	q := newFairQueue[int](FairnessWeighted)
+	q.key = func(obj int) string { return strconv.Itoa(obj % 5) }
+	q.weight = func(key string) int { return len(key) + int(key[0]-'0') }
+
+	for round := 0; round < 200; round++ {
+		for n := rnd.Intn(10); n > 0; n-- {
+			if rnd.Intn(4) == 0 {
+				q.pushFront(entry[int]{value: rnd.Intn(100)})
+			} else {
+				q.pushBack(entry[int]{value: rnd.Intn(100)})
+			}
+		}
+
+		peeked := make([]int, q.len())
+		for k := range peeked {
+			peeked[k] = q.at(k).value
+		}
+		pops := rnd.Intn(q.len() + 1)
+		for k := 0; k < pops; k++ {
+			if v := q.popFront().value; v != peeked[k] {
+				t.Fatalf("expected: %d at %d; got %d", peeked[k], k, v)
+			}
+		}
+	}
+}
@@ -6,71 +6,145 @@ package flashflood
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"math"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 const (
 	// the amount the channel will buffer
 	defaultChannelBuffer = 4096
+	// the maximum amount of elements the buffer is preallocated for, beyond it grows on demand
+	maxPreallocate = 1 << 16
+	// the amount of elements the buffer of a Keyed key is preallocated for
+	sinkPreallocate = 4
 	// the amount of the internal buffer, if buffer is full elements will be drained to channel
 	defaultBufferAmount = 256
 	// default time before the buffer times out and will start draining its contents to the channel
 	defaultTimeout = 100 * time.Millisecond
-	// default ticker time the buffer will check for activity (see defaultTimeout )
+	// default ticker time, deprecated: timeouts are deadline driven
 	defaultTickerTime = 10 * time.Millisecond
 	// default gate amount, open up the gate is this amount of elements need to be drained. (useful in conjunction with callback functions)
 	defaultGateAmount = int64(1)
+	// default time a flush blocks on a full channel before dropping the elements (see OverflowBlockWithTimeout)
+	defaultOverflowTimeout = 100 * time.Millisecond
+	// the amount of errors the Errors channel will buffer
+	defaultErrorBuffer = 64
 )
 
 const (
-	lastAction = iota
-	lastFlush
+	// no channel fetched yet
+	outputNone = iota
+	// elements are delivered one by one on the channel returned by GetChan
+	outputElements
+	// elements are delivered as batches on the channel returned by GetBatchChan
+	outputBatches
+	// elements are delivered as batches with their metadata on the channel returned by GetBatchInfoChan
+	outputBatchInfo
+	// batches are handed to a sink function (see Keyed)
+	outputSink
 )
 
 // New returns new instance with generic type parameter
 func New[T any](opts *Opts) *FlashFlood[T] {
 	opts = handleOpts(opts)
+	ff := newInstance[T](opts, preallocate(opts))
+	ff.floodChan = make(chan T, opts.ChannelBuffer)
+	ff.errs = make(chan error, defaultErrorBuffer)
+	ff.start()
+	return ff
+}
+
+// newSinkInstance returns an instance delivering through its sink instead of channels, the buffer of a Keyed key. It
+// shares the Errors channel of Keyed and starts with a small buffer, a Keyed may hold many of them
+func newSinkInstance[T any](opts *Opts, errs chan error, fetched *ChannelFetchedStatus) *FlashFlood[T] {
+	ff := newInstance[T](handleOpts(opts), sinkPreallocate)
+	ff.errs = errs
+	ff.channelFetched = fetched
+	ff.output.Store(outputSink)
+	ff.start()
+	return ff
+}
+
+// newInstance returns an instance without channels with a buffer preallocated for capacity elements, start it once
+// the channels are set
+func newInstance[T any](opts *Opts, capacity int) *FlashFlood[T] {
 	nfs := NewChannelFetchedStatus()
 
-	tickerCtx, tickerCancel := context.WithCancel(context.Background())
-	var tickerWg sync.WaitGroup
+	timerCtx, timerCancel := context.WithCancel(context.Background())
+	var timerWg sync.WaitGroup
 
 	ff := &FlashFlood[T]{
+		buffer:         newQueue[T](opts, capacity),
 		bufferAmount:   opts.BufferAmount,
 		channelFetched: &nfs,
 		debug:          opts.Debug,
-		floodChan:      make(chan T, opts.ChannelBuffer),
-		funcstack:      []FuncStack[T]{debugFunc[T]},
+		batchOnce:      &sync.Once{},
+		batchInfoOnce:  &sync.Once{},
+		batchSeq:       &atomic.Uint64{},
+		sent:           &atomic.Uint64{},
+		output:         &atomic.Int32{},
+		closed:         &atomic.Bool{},
+		stats:          &stats{},
+		spaceFreed:     make(chan struct{}),
+		funcstack:      []stackFunc[T]{debugFunc[T]},
 		gateAmount:     opts.GateAmount,
 
-		lastAction: &sync.Map{},
+		lastAction: &atomic.Int64{},
 
 		flushTimeout: opts.FlushTimeout,
 
 		flushEnabled: opts.FlushEnabled,
 
-		mutex:        &sync.Mutex{},
-		tickerCtx:    tickerCtx,
-		tickerCancel: &tickerCancel,
-		ticker:       time.NewTicker(opts.TickerTime),
-		tickerWg:     &tickerWg,
-		timeout:      opts.Timeout,
-		opts:         opts,
+		mutex:       &sync.Mutex{},
+		timerCtx:    timerCtx,
+		timerCancel: &timerCancel,
+		timerWg:     &timerWg,
+		wake:        make(chan struct{}, 1),
+		timeout:     opts.Timeout,
+		opts:        opts,
+		policy:      opts.FlushPolicy,
 
-		lastFlush: &sync.Map{},
+		lastFlush: &atomic.Int64{},
+	}
+	if ff.policy == nil {
+		ff.policy = DefaultFlushPolicy(opts)
 	}
-	ff.lastAction.Store(lastAction, time.Now())
+	ff.lastAction.Store(time.Now().UnixNano())
+	ff.lastFlush.Store(time.Now().UnixNano())
+	return ff
+}
 
-	if ff.flushEnabled {
-		ff.lastFlush.Store(lastFlush, time.Now())
+// start starts the dispatcher and the timer
+func (i *FlashFlood[T]) start() {
+	if i.opts.AsyncDispatch {
+		i.dispatcher = newDispatcher[T]()
+		go handleDispatch[T](i)
 	}
 
-	// Start ticker goroutine after all initialization is complete
-	go handleTicker[T](ff)
-	return ff
+	// Start timer after all initialization is complete
+	if i.opts.Scheduler != nil {
+		i.opts.Scheduler.register(i)
+	} else {
+		i.timerWg.Add(1)
+		go handleTimer[T](i)
+	}
+}
+
+// preallocate returns the capacity of the buffer in its steady state: the ring plus an incomplete gate, or the hard cap
+func preallocate(opts *Opts) int {
+	capacity := opts.BufferAmount + opts.GateAmount
+	if opts.MaxBufferAmount > capacity {
+		capacity = opts.MaxBufferAmount
+	}
+	if capacity > maxPreallocate {
+		capacity = maxPreallocate
+	}
+	return int(capacity)
 }
 
 func handleOpts(opts *Opts) (defaultOpts *Opts) {
@@ -103,273 +177,678 @@ func handleOpts(opts *Opts) (defaultOpts *Opts) {
 		opts.GateAmount = defaultOpts.GateAmount
 	}
 
+	if opts.OverflowTimeout == 0 {
+		opts.OverflowTimeout = defaultOverflowTimeout
+	}
+
 	return opts
 }
 
-// Close Cleanup resources and kill timers/tickers etc
+// Close Cleanup resources and kill timers/tickers etc, elements left in the buffer are dropped (see Shutdown)
 func (i *FlashFlood[T]) Close() {
-	// Stop ticker and wait for goroutine to finish
-	(*i.tickerCancel)()
-	i.tickerWg.Wait()
+	if i.close() {
+		close(i.errs)
+	}
+}
 
-	// Now it's safe to modify fields since ticker goroutine has stopped
+// close does the work of Close except closing the Errors channel, false when the instance was closed already
+func (i *FlashFlood[T]) close() bool {
+	// Stop timer and wait for goroutine to finish
+	i.haltTimer()
+
+	// Now it's safe to modify fields since timer goroutine has stopped
 	i.mutex.Lock()
+	if i.closed.Load() {
+		i.mutex.Unlock()
+		return false
+	}
+	i.closed.Store(true)
 
-	i.channelFetched = nil
-	i.floodChan = nil
+	if i.buffer.len() != 0 || i.delayed.len() != 0 || i.queued() != 0 {
+		log.Println("Close called on non empty buffer")
+	}
 
-	i.funcstack = nil
-	i.lastAction = nil
+	i.buffer.reset()
+	i.delayed.reset()
+	i.signalSpace()
+	i.mutex.Unlock()
 
-	i.opts = nil
+	// the dispatcher reports errors and takes the lock, stop it without holding the lock
+	i.haltDispatch()
+	return true
+}
 
-	if len(i.buffer) != 0 {
-		log.Println("Close called on non empty buffer")
+// closeIfEmpty closes the instance when it holds no elements (buffered, held back or queued for the dispatcher), false
+// when it holds elements or was closed already
+func (i *FlashFlood[T]) closeIfEmpty() bool {
+	i.mutex.Lock()
+	if i.closed.Load() || i.buffer.len() != 0 || i.delayed.len() != 0 || i.queued() != 0 {
+		i.mutex.Unlock()
+		return false
 	}
+	// no Push gets passed this point anymore, so the instance stays empty
+	i.closed.Store(true)
+	i.signalSpace()
+	i.mutex.Unlock()
 
-	i.buffer = nil
+	i.haltTimer()
+	i.haltDispatch()
+	return true
+}
 
-	i.mutex.Unlock()
-	i.mutex = nil
+// Shutdown stops accepting new elements, flushes the remaining buffer through the FuncStack to the fetched channel
+// and closes the channel so consumers ranging over it terminate. The flush is aborted when ctx is done
+func (i *FlashFlood[T]) Shutdown(ctx context.Context) error {
+	err := i.shutdown(ctx, ReasonShutdown)
+	if err == ErrClosed {
+		return err
+	}
+	i.closeOutput()
+	return err
 }
 
-func handleTicker[T any](i *FlashFlood[T]) {
-	i.tickerWg.Add(1)
-	defer i.tickerWg.Done()
+// shutdown does the work of Shutdown except closing the channels, the remaining buffer is flushed with reason
+func (i *FlashFlood[T]) shutdown(ctx context.Context, reason FlushReason) error {
+	i.mutex.Lock()
+	if i.closed.Load() {
+		i.mutex.Unlock()
+		return ErrClosed
+	}
+	// no Push, Get or Drain gets passed this point anymore
+	i.closed.Store(true)
+	i.signalSpace()
+	i.mutex.Unlock()
 
-	run := true
-	var elapsed time.Duration
+	i.haltTimer()
 
-	for run {
-		select {
-		case <-i.tickerCtx.Done():
-			run = false
-		case <-i.ticker.C:
-
-			if e, ok := i.lastAction.Load(lastAction); ok {
-				elapsed = time.Since(e.(time.Time))
-			}
-
-			if elapsed > i.timeout {
-				_, _ = i.Drain(true, false)
-			} else {
-				if i.flushEnabled {
-
-					if e, ok := i.lastFlush.Load(lastFlush); ok {
-						elapsed = time.Since(e.(time.Time))
-					}
-
-					if elapsed > i.flushTimeout {
-						_, _ = i.Drain(true, true)
-					}
-				}
-			}
+	i.mutex.Lock()
+	// nothing is held back anymore, the delayed elements are flushed with the rest
+	for i.delayed.len() > 0 {
+		i.buffer.pushBack(i.newEntry(i.delayed.pop(), time.Now().UnixNano()))
+	}
+	i.expire(time.Now())
+	var err error
+	if i.buffer.len() != 0 {
+		if (*i.channelFetched).IsChannelFetched() {
+			err = i.flushGated(ctx, i.buffer.len(), false, reason)
+		} else {
+			err = ErrNotFetched
 		}
 	}
+	i.buffer.reset()
+	i.mutex.Unlock()
 
-	i.ticker.Stop()
-	i.ticker.C = nil
-	i.ticker = nil
+	if dispatchErr := i.stopDispatch(ctx); err == nil {
+		err = dispatchErr
+	}
+	return err
 }
 
-func (i *FlashFlood[T]) handleDrainObjs() []T {
-	if i.opts.DisableRingUntilChanActive && !(*i.channelFetched).IsChannelFetched() {
-		return nil
+// closeOutput closes the channels, call it once nothing sends on them anymore: every flush checks closed and the
+// dispatcher stopped
+func (i *FlashFlood[T]) closeOutput() {
+	close(i.floodChan)
+	switch i.output.Load() {
+	case outputBatches:
+		close(i.batchChan)
+	case outputBatchInfo:
+		close(i.batchInfoChan)
 	}
+	close(i.errs)
+}
 
-	var drainObjs []T
-	bl := int64(len(i.buffer))
-	toDrain := bl - i.bufferAmount
+// release flushes the elements released by the flush policy, with respectGate an incomplete last batch stays in the
+// buffer. Make sure we have a mutex Lock
+func (i *FlashFlood[T]) release(now time.Time, respectGate bool) {
+	i.expire(now)
+	if i.buffer.len() == 0 || i.paused || now.UnixNano() < i.retryAt {
+		return
+	}
 
-	if i.gateAmount == 1 {
-		if toDrain > 0 {
-			drainObjs, i.buffer = i.buffer[0:toDrain], i.buffer[toDrain:]
-		}
-	} else {
-		if toDrain > 0 && toDrain >= i.gateAmount {
-			drainObjs, i.buffer = i.buffer[0:i.gateAmount], i.buffer[i.gateAmount:]
-		}
+	toDrain, reason := i.policy.Release(i.bufferStats(now))
+	if toDrain <= 0 {
+		return
+	}
+	if toDrain > i.buffer.len() {
+		toDrain = i.buffer.len()
 	}
 
-	return drainObjs
+	if err := i.flushGated(context.Background(), toDrain, respectGate, reason); err != nil {
+		i.reportError(err)
+	}
+	i.signalSpace()
 }
 
-// Push add objects to buffer
+// bufferStats returns the snapshot handed to the flush policy, make sure we have a mutex Lock
+func (i *FlashFlood[T]) bufferStats(now time.Time) BufferStats {
+	s := BufferStats{
+		Count:      i.buffer.len(),
+		Bytes:      i.bytes,
+		Idle:       now.Sub(time.Unix(0, i.lastAction.Load())),
+		SinceFlush: now.Sub(time.Unix(0, i.lastFlush.Load())),
+	}
+	if i.buffer.len() > 0 {
+		s.OldestAge = now.Sub(time.Unix(0, i.buffer.oldest()))
+	}
+	if q, ok := i.buffer.(*deadlineQueue[T]); ok {
+		due, next := q.due(now.Add(i.opts.DeadlineSlack).UnixNano())
+		s.Due = due
+		s.NextDue = time.Duration(next-now.UnixNano()) - i.opts.DeadlineSlack
+	}
+	return s
+}
+
+// Push add objects to buffer, blocks while the buffer is at MaxBufferAmount
 func (i *FlashFlood[T]) Push(objs ...T) error {
-	i.mutex.Lock()
-	i.buffer = append(i.buffer, objs...)
-	drainObjs := i.handleDrainObjs()
-	if drainObjs != nil {
-		i.flush2Channel(drainObjs, false, false)
+	return i.PushContext(context.Background(), objs...)
+}
+
+// PushContext add objects to buffer, blocks until there is room below MaxBufferAmount or ctx is done
+func (i *FlashFlood[T]) PushContext(ctx context.Context, objs ...T) error {
+	return i.pushContext(ctx, placement{}, objs)
+}
+
+func (i *FlashFlood[T]) pushContext(ctx context.Context, p placement, objs []T) error {
+	if i.opts.MaxBufferAmount > 0 && int64(len(objs)) > i.opts.MaxBufferAmount {
+		return ErrFull
 	}
+	i.mutex.Lock()
+	fits := i.withinQuota(objs, false)
 	i.mutex.Unlock()
-	i.Ping()
+	if !fits {
+		return ErrFull
+	}
+
+	for {
+		i.mutex.Lock()
+		if i.closed.Load() {
+			i.mutex.Unlock()
+			return ErrClosed
+		}
+		if i.hasRoom(objs) {
+			i.pushLocked(objs, p)
+			i.unlock()
+			return nil
+		}
+		freed := i.spaceFreed
+		i.mutex.Unlock()
+
+		select {
+		case <-freed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// TryPush add objects to buffer, returns ErrFull instead of blocking when they do not fit below MaxBufferAmount
+func (i *FlashFlood[T]) TryPush(objs ...T) error {
+	i.mutex.Lock()
+	if i.closed.Load() {
+		i.mutex.Unlock()
+		return ErrClosed
+	}
+	if !i.hasRoom(objs) {
+		i.mutex.Unlock()
+		return ErrFull
+	}
+	i.pushLocked(objs, placement{})
+	i.unlock()
 	return nil
 }
 
-// Unshift add objects to the front of buffer
-func (i *FlashFlood[T]) Unshift(objs ...T) error {
+func (i *FlashFlood[T]) pushLocked(objs []T, p placement) {
+	now := time.Now()
+	if p.at > now.UnixNano() {
+		i.delay(objs, p.at)
+		return
+	}
+	i.armTimer()
+	for _, obj := range objs {
+		e := i.newEntry(obj, now.UnixNano())
+		if p.ttl > 0 {
+			i.expireAt(&e, now.Add(p.ttl).UnixNano())
+		}
+
+		switch q := i.buffer.(type) {
+		case *priorityQueue[T]:
+			q.pushAt(p.level, e)
+		case *deadlineQueue[T]:
+			q.pushDeadline(e, p.deadline)
+		default:
+			i.buffer.pushBack(e)
+		}
+	}
+	i.lastAction.Store(now.UnixNano())
+	i.releaseOnPush(now)
+}
+
+// releaseOnPush consults the flush policy after elements were added, make sure we have a mutex Lock
+func (i *FlashFlood[T]) releaseOnPush(now time.Time) {
+	// the push may have changed the mind of a policy the timer gave up on
+	i.stalled = false
+	if i.parked {
+		i.parked = false
+		i.wakeTimer()
+	}
+	if i.opts.DisableRingUntilChanActive && !(*i.channelFetched).IsChannelFetched() {
+		return
+	}
+	i.release(now, true)
+}
+
+func (i *FlashFlood[T]) newEntry(obj T, pushed int64) entry[T] {
+	e := entry[T]{value: obj, pushed: pushed}
+	if i.sizer != nil {
+		e.size = i.sizer(obj)
+		i.bytes += e.size
+	}
+	if i.opts.ElementTTL > 0 {
+		i.expireAt(&e, pushed+int64(i.opts.ElementTTL))
+	}
+	return e
+}
+
+// SetSizer sets the function returning the size of an element, used for BufferStats.Bytes, GateBytes and
+// MaxBatchBytes. Set it before pushing, elements already in the buffer count as 0 bytes
+func (i *FlashFlood[T]) SetSizer(f func(obj T) int) {
 	i.mutex.Lock()
 	defer i.mutex.Unlock()
+	i.sizer = f
+}
 
-	i.buffer = append(objs, i.buffer...)
-	drainObjs := i.handleDrainObjs()
-	if drainObjs != nil {
-		i.flush2Channel(drainObjs, false, false)
+// Unshift add objects to the front of buffer (of the highest priority level), returns ErrFull when they do not fit below MaxBufferAmount
+func (i *FlashFlood[T]) Unshift(objs ...T) error {
+	i.mutex.Lock()
+	defer i.unlock()
+	if i.closed.Load() {
+		return ErrClosed
+	}
+	if !i.hasRoom(objs) {
+		return ErrFull
+	}
+
+	now := time.Now()
+	i.armTimer()
+	// unshifted elements take over the age of the oldest element, so the front of the buffer always holds the oldest
+	pushed := now.UnixNano()
+	if i.buffer.len() > 0 && i.buffer.oldest() < pushed {
+		pushed = i.buffer.oldest()
+	}
+	for k := len(objs) - 1; k >= 0; k-- {
+		i.buffer.pushFront(i.newEntry(objs[k], pushed))
+	}
+	i.lastAction.Store(now.UnixNano())
+	i.releaseOnPush(now)
+	return nil
+}
+
+// hasRoom reports if objs fit in the buffer below MaxBufferAmount and KeyQuota, make sure we have a mutex Lock
+func (i *FlashFlood[T]) hasRoom(objs []T) bool {
+	if i.opts.MaxBufferAmount > 0 && int64(i.buffer.len()+i.delayed.len()+i.queued()+len(objs)) > i.opts.MaxBufferAmount {
+		return false
+	}
+	return i.withinQuota(objs, true)
+}
+
+// signalSpace wakes up the producers blocked in PushContext, make sure we have a mutex Lock
+func (i *FlashFlood[T]) signalSpace() {
+	if i.opts.MaxBufferAmount == 0 && i.opts.KeyQuota == 0 {
+		return
+	}
+	close(i.spaceFreed)
+	i.spaceFreed = make(chan struct{})
+}
+
+// cut removes amount elements from the front of the buffer and returns them with the metadata of the batch, make
+// sure we have a mutex Lock
+func (i *FlashFlood[T]) cut(amount int, reason FlushReason) ([]T, BatchInfo) {
+	if amount > i.buffer.len() {
+		amount = i.buffer.len()
+	}
+	return i.cutInto(make([]T, amount), reason)
+}
+
+// cutInto removes len(objs) elements from the front of the buffer into objs, make sure we have a mutex Lock
+func (i *FlashFlood[T]) cutInto(objs []T, reason FlushReason) ([]T, BatchInfo) {
+	info := BatchInfo{Reason: reason, Size: len(objs)}
+	if len(objs) == 0 {
+		return objs, info
+	}
+	info.Seq = i.batchSeq.Add(1)
+	// the buffer does not release in push order with Fairness
+	first, last := int64(math.MaxInt64), int64(math.MinInt64)
+	for k := range objs {
+		e := i.buffer.popFront()
+		objs[k] = e.value
+		i.bytes -= e.size
+		info.Bytes += e.size
+		first = min(first, e.pushed)
+		last = max(last, e.pushed)
+	}
+	info.FirstPush = time.Unix(0, first)
+	info.LastPush = time.Unix(0, last)
+	return objs, info
+}
+
+// batchSlice returns a slice for a batch of size elements. When the batch can not outlive the flush (sent element by
+// element or dropped, without FuncStack or dispatcher) the slice is reused, so the steady state of Push does not
+// allocate
+func (i *FlashFlood[T]) batchSlice(size int) []T {
+	output := i.output.Load()
+	if i.dispatcher != nil || (output != outputElements && output != outputNone) || len(i.funcstack) > 1 {
+		return make([]T, size)
+	}
+	if cap(i.scratch) < size {
+		i.scratch = make([]T, size)
+	}
+	return i.scratch[:size]
+}
+
+// own returns objs, copied when it is the reused slice of batchSlice. A batch channel fetched after batchSlice decided
+// on reuse must not receive it
+func (i *FlashFlood[T]) own(objs []T) []T {
+	if i.dispatcher != nil || len(objs) == 0 || cap(i.scratch) == 0 || &objs[0] != &i.scratch[:1][0] {
+		return objs
+	}
+	return append([]T(nil), objs...)
+}
+
+// flushGated cuts amount elements from the front of the buffer and flushes them in batches of GateAmount, GateBytes
+// and MaxBatchBytes. With respectGate an incomplete last batch stays in the buffer
+func (i *FlashFlood[T]) flushGated(ctx context.Context, amount int, respectGate bool, reason FlushReason) error {
+	for amount > 0 {
+		size, complete := i.nextBatch(amount)
+		if respectGate && !complete {
+			return nil
+		}
+		objs, info := i.cutInto(i.batchSlice(size), reason)
+		if err := i.flushBatch(ctx, objs, info); err != nil {
+			return err
+		}
+		amount -= size
 	}
-	i.Ping()
 	return nil
 }
 
-func (i *FlashFlood[T]) flush2Channel(objs []T, isInteralBuffer bool, respectGate bool) {
-	bl := int64(len(objs))
-
-	if bl > 0 && (*i.channelFetched).IsChannelFetched() {
-
-		if isInteralBuffer {
-			if i.gateAmount > 1 {
-				if bl >= i.gateAmount {
-					objs, i.buffer = objs[0:i.gateAmount], objs[i.gateAmount:]
-				} else {
-					if !respectGate {
-						objs = i.buffer
-						i.clearBuffer()
-					}
-				}
-			} else {
-				objs = i.buffer
-				i.clearBuffer()
-			}
+// nextBatch returns the size of the batch at the front of the buffer within amount elements, and if it completes a
+// gate. Make sure we have a mutex Lock
+func (i *FlashFlood[T]) nextBatch(amount int) (int, bool) {
+	size, bytes := 0, 0
+	for size < amount {
+		elementBytes := i.buffer.at(size).size
+		// a single element exceeding MaxBatchBytes is a batch on its own
+		if i.opts.MaxBatchBytes > 0 && size > 0 && bytes+elementBytes > i.opts.MaxBatchBytes {
+			return size, true
 		}
-		blAfter := int64(len(objs))
+		size++
+		bytes += elementBytes
 
-		for _, f := range i.funcstack {
-			objs = f(objs, i)
+		if i.gateAmount > 1 && int64(size) == i.gateAmount {
+			return size, true
+		}
+		if i.opts.GateBytes > 0 && bytes >= i.opts.GateBytes {
+			return size, true
 		}
+	}
+	return size, i.gateAmount <= 1 && i.opts.GateBytes == 0
+}
+
+// flushBatch runs objs through the FuncStack and sends them to the fetched channel, or hands them to the dispatcher
+// with AsyncDispatch. ctx aborts a send blocking on a full channel. Without a fetched channel the elements are dropped
+// (ring behavior). Make sure we have a mutex Lock
+func (i *FlashFlood[T]) flushBatch(ctx context.Context, objs []T, info BatchInfo) error {
+	if len(objs) == 0 || !(*i.channelFetched).IsChannelFetched() {
+		return nil
+	}
 
-		for _, v := range objs {
-			i.floodChan <- v
+	if i.dispatcher != nil {
+		i.enqueue(objs, info)
+		return nil
+	}
+
+	err := i.deliver(ctx, objs, info)
+	if errors.Is(err, ErrFuncStack) {
+		i.failBatch(objs, info, err)
+	}
+	return err
+}
+
+// deliver runs objs through the FuncStack and sends them to the fetched channel
+func (i *FlashFlood[T]) deliver(ctx context.Context, objs []T, info BatchInfo) error {
+	objs, err := i.applyFuncs(objs, info)
+	if err != nil {
+		return err
+	}
+
+	switch i.output.Load() {
+	case outputBatches:
+		objs = i.own(objs)
+		if len(objs) > 0 {
+			// clip the batch, so appending to it can never overwrite elements of another batch
+			return send(ctx, i.batchChan, objs[:len(objs):len(objs)], i.opts, i.dropBatch, i.sent)
 		}
+		return nil
+	case outputBatchInfo:
+		objs = i.own(objs)
+		if len(objs) > 0 {
+			return send(ctx, i.batchInfoChan, Batch[T]{Items: objs[:len(objs):len(objs)], Info: info}, i.opts, i.dropBatchInfo, i.sent)
+		}
+		return nil
+	case outputSink:
+		if len(objs) > 0 {
+			return i.sink(ctx, objs[:len(objs):len(objs)], info)
+		}
+		return nil
+	}
 
-		if isInteralBuffer && len(i.buffer) > 0 && blAfter < bl {
-			i.flush2Channel(i.buffer, true, respectGate)
+	for _, v := range objs {
+		if err := send(ctx, i.floodChan, v, i.opts, i.dropElement, i.sent); err != nil {
+			return err
 		}
 	}
+	return nil
 }
 
 // GetChan get the overflow channel
 func (i *FlashFlood[T]) GetChan() (<-chan T, error) {
-	(*i.channelFetched).ChannelFetched()
+	if i.closed.Load() {
+		return nil, ErrClosed
+	}
+	if !i.setOutput(outputElements) {
+		return nil, ErrOutputMode
+	}
 	return i.floodChan, nil
 }
 
-// Purge clears buffer
+// GetBatchChan get the overflow channel delivering every gate, timeout or manual flush as a single slice.
+// Once fetched the elements are no longer delivered on the channel of GetChan (and vice versa)
+func (i *FlashFlood[T]) GetBatchChan() (<-chan []T, error) {
+	if i.closed.Load() {
+		return nil, ErrClosed
+	}
+	i.initBatchChan()
+	if !i.setOutput(outputBatches) {
+		return nil, ErrOutputMode
+	}
+	return i.batchChan, nil
+}
+
+func (i *FlashFlood[T]) initBatchChan() {
+	i.batchOnce.Do(func() {
+		i.batchChan = make(chan []T, i.opts.ChannelBuffer)
+	})
+}
+
+func (i *FlashFlood[T]) setOutput(mode int32) bool {
+	if !i.output.CompareAndSwap(outputNone, mode) && i.output.Load() != mode {
+		return false
+	}
+	(*i.channelFetched).ChannelFetched()
+	return true
+}
+
+// Purge clears buffer, including the elements held back by PushAt
 func (i *FlashFlood[T]) Purge() error {
 	i.mutex.Lock()
 	defer i.mutex.Unlock()
+	if i.closed.Load() {
+		return ErrClosed
+	}
 	i.clearBuffer()
+	i.delayed.reset()
+	i.signalSpace()
 	return nil
 }
 
 // Ping updates lastaction to postpone timeout
 func (i *FlashFlood[T]) Ping() {
-	i.lastAction.Store(lastAction, time.Now())
+	if i.closed.Load() {
+		return
+	}
+	i.lastAction.Store(time.Now().UnixNano())
 }
 
 // Count returns amount of elements in buffer
 func (i *FlashFlood[T]) Count() uint64 {
 	i.mutex.Lock()
 	defer i.mutex.Unlock()
-	cnt := uint64(len(i.buffer))
+	cnt := uint64(i.buffer.len())
 	return cnt
 }
 
+// Stats returns the counters of the instance
+func (i *FlashFlood[T]) Stats() Stats {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	st := Stats{
+		Buffered: uint64(i.buffer.len()),
+		Dropped:  i.stats.dropped.Load(),
+		Failed:   i.stats.failed.Load(),
+		Panics:   i.stats.panics.Load(),
+		Expired:  i.stats.expired.Load(),
+		Delayed:  uint64(i.delayed.len()),
+		Queued:   uint64(i.queued()),
+	}
+	if q, ok := i.buffer.(*priorityQueue[T]); ok {
+		st.Priority = q.stats()
+	}
+	return st
+}
+
 func (i *FlashFlood[T]) clearBuffer() {
 	// make sure we have a mutex Lock
 	i.Ping()
-	i.buffer = nil
+	i.buffer.reset()
+	i.bytes = 0
+	i.nextExpiry = 0
 }
 
 // Get amount of elements from buffer
 func (i *FlashFlood[T]) Get(amount int) ([]T, error) {
-	var drainObjs []T
-
 	i.mutex.Lock()
-	bl := len(i.buffer)
+	if i.closed.Load() {
+		i.mutex.Unlock()
+		return nil, ErrClosed
+	}
+	i.expire(time.Now())
+	bl := i.buffer.len()
 	if bl == 0 {
 		i.mutex.Unlock()
 		return nil, nil
 	}
 
+	drainObjs, info := i.cut(amount, ReasonGet)
 	if bl <= amount {
-		objs := i.buffer
 		i.clearBuffer()
-		i.mutex.Unlock()
-
-		for _, f := range i.funcstack {
-			objs = f(objs, i)
-		}
-
-		return objs, nil
 	}
-
-	drainObjs, i.buffer = i.buffer[0:amount], i.buffer[amount:]
+	i.signalSpace()
 	i.mutex.Unlock()
-	for _, f := range i.funcstack {
-		drainObjs = f(drainObjs, i)
+
+	objs, err := i.applyFuncs(drainObjs, info)
+	if err != nil {
+		i.mutex.Lock()
+		i.failBatch(drainObjs, info, err)
+		i.mutex.Unlock()
+		return nil, err
 	}
-	return drainObjs, nil
+	return objs, nil
 }
 
 // GetOnChan amount of elements from buffer, flush to channel
 func (i *FlashFlood[T]) GetOnChan(amount int) error {
-	drainObjs, err := i.Get(amount)
-	_ = err
-	// TODO implement error handling once Get can throw an error
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	if i.closed.Load() {
+		return ErrClosed
+	}
 
-	i.flush2Channel(drainObjs, false, false)
+	i.expire(time.Now())
+	bl := i.buffer.len()
+	drainObjs, info := i.cut(amount, ReasonGetOnChan)
+	if bl <= amount {
+		i.clearBuffer()
+	}
+	i.signalSpace()
 
-	return nil
+	return i.flushBatch(context.Background(), drainObjs, info)
 }
 
 // Drain drains buffer into channel or as slice (onChannel bool)
 func (i *FlashFlood[T]) Drain(onChannel bool, respectGate bool) ([]T, error) {
-	i.lastFlush.Store(lastFlush, time.Now())
+	i.lastFlush.Store(time.Now().UnixNano())
 
 	i.mutex.Lock()
 	defer i.mutex.Unlock()
+	if i.closed.Load() {
+		return nil, ErrClosed
+	}
 
-	if len(i.buffer) == 0 {
+	i.expire(time.Now())
+	if i.buffer.len() == 0 {
 		return nil, nil
 	}
 
 	if onChannel {
-		i.flush2Channel(i.buffer, true, respectGate)
-		i.clearBuffer()
-		return nil, nil
+		err := i.flushGated(context.Background(), i.buffer.len(), false, ReasonDrain)
+		// a retained batch stays in the buffer
+		if i.buffer.len() == 0 {
+			i.clearBuffer()
+		}
+		i.signalSpace()
+		return nil, err
 	}
 
-	objs := i.buffer
+	objs, info := i.cut(i.buffer.len(), ReasonDrain)
 	i.clearBuffer()
+	i.signalSpace()
 
-	for _, f := range i.funcstack {
-		objs = f(objs, i)
+	drainObjs, err := i.applyFuncs(objs, info)
+	if err != nil {
+		i.failBatch(objs, info, err)
+		return nil, err
 	}
-
-	return objs, nil
+	return drainObjs, nil
 }
 
 // AddFunc add a "callback" function to the callstack to be performed on the objects drained
 func (i *FlashFlood[T]) AddFunc(f FuncStack[T]) {
+	i.addFunc(func(objs []T, _ BatchInfo, ff *FlashFlood[T]) ([]T, error) {
+		return f(objs, ff), nil
+	})
+}
+
+// addFunc adds f to the callstack, the debug handler stays last
+func (i *FlashFlood[T]) addFunc(f stackFunc[T]) {
 	l := len(i.funcstack)
 	debughandler := i.funcstack[l-1]
 	i.funcstack[l-1] = f
 	i.funcstack = append(i.funcstack, debughandler)
 }
 
-func debugFunc[T any](i []T, ff *FlashFlood[T]) []T {
+func debugFunc[T any](i []T, _ BatchInfo, ff *FlashFlood[T]) ([]T, error) {
 	if ff.debug {
 		fmt.Printf("DEBUG: %#v\n", i)
 	}
-	return i
+	return i, nil
 }
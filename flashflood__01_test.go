@@ -64,6 +64,7 @@ func TestPush(t *testing.T) {
 
 func BenchmarkPushChan(b *testing.B) {
 	b.StopTimer()
+	b.ReportAllocs()
 
 	ff := flashflood.New[TestObj](&flashflood.Opts{
 		Timeout:       10 * time.Millisecond,
@@ -83,6 +84,7 @@ func BenchmarkPushChan(b *testing.B) {
 
 func BenchmarkPushNoChan(b *testing.B) {
 	b.StopTimer()
+	b.ReportAllocs()
 
 	ff := flashflood.New[TestObj](&flashflood.Opts{
 		Timeout:      10 * time.Millisecond,
@@ -101,6 +103,7 @@ func BenchmarkPushNoChan(b *testing.B) {
 
 func BenchmarkPushChanGate(b *testing.B) {
 	b.StopTimer()
+	b.ReportAllocs()
 
 	ff := flashflood.New[TestObj](&flashflood.Opts{
 		Timeout:       10 * time.Millisecond,
@@ -121,6 +124,7 @@ func BenchmarkPushChanGate(b *testing.B) {
 
 func BenchmarkPushChanBigBuffer(b *testing.B) {
 	b.StopTimer()
+	b.ReportAllocs()
 
 	ff := flashflood.New[TestObj](&flashflood.Opts{
 		Timeout:       10 * time.Millisecond,
@@ -140,6 +144,7 @@ func BenchmarkPushChanBigBuffer(b *testing.B) {
 
 func BenchmarkWithGet(b *testing.B) {
 	b.StopTimer()
+	b.ReportAllocs()
 
 	ff := flashflood.New[TestObj](&flashflood.Opts{
 		Timeout:       10 * time.Millisecond,
@@ -160,6 +165,7 @@ func BenchmarkWithGet(b *testing.B) {
 
 func BenchmarkPushChanBigBufferPow(b *testing.B) {
 	b.StopTimer()
+	b.ReportAllocs()
 
 	ff := flashflood.New[TestObj](&flashflood.Opts{
 		Timeout:       10 * time.Millisecond,
@@ -188,6 +194,7 @@ func BenchmarkPushChanBigBufferPow(b *testing.B) {
 
 func BenchmarkPushChanBigBufferPowCBFuncWithGate(b *testing.B) {
 	b.StopTimer()
+	b.ReportAllocs()
 
 	ff := flashflood.New[TestObj](&flashflood.Opts{
 		Timeout:       10 * time.Millisecond,
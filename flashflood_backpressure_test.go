@@ -0,0 +1,118 @@
+package flashflood_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/thisisdevelopment/flashflood/v2"
+)
+
+func newBoundedFF() *flashflood.FlashFlood[int] {
+	return flashflood.New[int](&flashflood.Opts{
+		BufferAmount:               10,
+		MaxBufferAmount:            3,
+		Timeout:                    time.Second,
+		DisableRingUntilChanActive: true,
+	})
+}
+
+func TestTryPushFull(t *testing.T) {
+	ff := newBoundedFF()
+	defer ff.Close()
+
+	if err := ff.TryPush(1, 2, 3); err != nil {
+		t.Fatalf("could not push: %v", err)
+	}
+	if err := ff.TryPush(4); !errors.Is(err, flashflood.ErrFull) {
+		t.Fatalf("expected: %v; got %v", flashflood.ErrFull, err)
+	}
+	if err := ff.Unshift(4); !errors.Is(err, flashflood.ErrFull) {
+		t.Fatalf("expected: %v; got %v", flashflood.ErrFull, err)
+	}
+	if ff.Count() != 3 {
+		t.Fatalf("expected 3 in buffer; got %v", ff.Count())
+	}
+}
+
+func TestPushContextTooMany(t *testing.T) {
+	ff := newBoundedFF()
+	defer ff.Close()
+
+	if err := ff.PushContext(context.Background(), 1, 2, 3, 4); !errors.Is(err, flashflood.ErrFull) {
+		t.Fatalf("expected: %v; got %v", flashflood.ErrFull, err)
+	}
+}
+
+func TestPushContextDeadline(t *testing.T) {
+	ff := newBoundedFF()
+	defer ff.Close()
+
+	_ = ff.Push(1, 2, 3)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if err := ff.PushContext(ctx, 4); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected: %v; got %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestPushContextUnblocks(t *testing.T) {
+	ff := newBoundedFF()
+	defer ff.Close()
+
+	_ = ff.Push(1, 2, 3)
+
+	done := make(chan error)
+	go func() {
+		done <- ff.PushContext(context.Background(), 4, 5)
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	_, _ = ff.Get(1)
+
+	select {
+	case err := <-done:
+		t.Fatalf("expected: blocked push; got %v", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	_, _ = ff.Get(1)
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("could not push: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected: push to unblock; got nothing")
+	}
+
+	if ff.Count() != 3 {
+		t.Fatalf("expected 3 in buffer; got %v", ff.Count())
+	}
+}
+
+func TestPushContextClose(t *testing.T) {
+	ff := newBoundedFF()
+	_ = ff.Push(1, 2, 3)
+
+	done := make(chan error)
+	go func() {
+		done <- ff.PushContext(context.Background(), 4)
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	ff.Close()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, flashflood.ErrClosed) {
+			t.Fatalf("expected: %v; got %v", flashflood.ErrClosed, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected: push to unblock; got nothing")
+	}
+}
@@ -0,0 +1,105 @@
+package flashflood_test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/thisisdevelopment/flashflood/v2"
+)
+
+func TestGetBatchChanGate(t *testing.T) {
+	ff := flashflood.New[int](&flashflood.Opts{
+		BufferAmount: 2,
+		GateAmount:   3,
+		Timeout:      50 * time.Millisecond,
+	})
+	defer ff.Close()
+
+	ch, err := ff.GetBatchChan()
+	if err != nil {
+		t.Fatalf("could not get batch channel: %v", err)
+	}
+
+	_ = ff.Push(1, 2, 3, 4, 5)
+
+	select {
+	case batch := <-ch:
+		if !reflect.DeepEqual(batch, []int{1, 2, 3}) {
+			t.Fatalf("expected: %v; got %v", []int{1, 2, 3}, batch)
+		}
+	default:
+		t.Fatalf("expected: gate batch; got nothing")
+	}
+
+	select {
+	case batch := <-ch:
+		if !reflect.DeepEqual(batch, []int{4, 5}) {
+			t.Fatalf("expected: %v; got %v", []int{4, 5}, batch)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatalf("expected: timeout batch; got nothing")
+	}
+}
+
+func TestGetBatchChanDrain(t *testing.T) {
+	ff := flashflood.New[int](&flashflood.Opts{
+		BufferAmount: 10,
+		Timeout:      time.Second,
+	})
+	defer ff.Close()
+
+	ch, _ := ff.GetBatchChan()
+	_ = ff.Push(1, 2, 3)
+	_, _ = ff.Drain(true, false)
+
+	select {
+	case batch := <-ch:
+		if !reflect.DeepEqual(batch, []int{1, 2, 3}) {
+			t.Fatalf("expected: %v; got %v", []int{1, 2, 3}, batch)
+		}
+		if cap(batch) != len(batch) {
+			t.Fatalf("expected: clipped batch; got cap %d for len %d", cap(batch), len(batch))
+		}
+	default:
+		t.Fatalf("expected: drained batch; got nothing")
+	}
+}
+
+func TestGetBatchChanOutputMode(t *testing.T) {
+	ff := flashflood.New[int](&flashflood.Opts{})
+	defer ff.Close()
+
+	if _, err := ff.GetChan(); err != nil {
+		t.Fatalf("could not get channel: %v", err)
+	}
+	if _, err := ff.GetBatchChan(); !errors.Is(err, flashflood.ErrOutputMode) {
+		t.Fatalf("expected: %v; got %v", flashflood.ErrOutputMode, err)
+	}
+	if _, err := ff.GetChan(); err != nil {
+		t.Fatalf("expected: GetChan to be callable twice; got %v", err)
+	}
+}
+
+// example using GetBatchChan, every gate is delivered as one slice
+func ExampleFlashFlood_GetBatchChan() {
+	ff := flashflood.New[string](&flashflood.Opts{
+		BufferAmount: 1,
+		GateAmount:   2,
+		Timeout:      100 * time.Millisecond,
+	})
+	defer ff.Close()
+
+	ch, _ := ff.GetBatchChan()
+	_ = ff.Push("a", "b", "c", "d", "e")
+
+	// the first gate is released by the push, the rest once the timeout hits
+	fmt.Println("BATCH:", <-ch)
+	fmt.Println("BATCH:", <-ch)
+	fmt.Println("BATCH:", <-ch)
+	// Output: BATCH: [a b]
+	// BATCH: [c d]
+	// BATCH: [e]
+}
@@ -0,0 +1,132 @@
+package flashflood_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/thisisdevelopment/flashflood/v2"
+)
+
+func TestGetBatchInfoChanReasons(t *testing.T) {
+	ff := flashflood.New[int](&flashflood.Opts{
+		BufferAmount: 2,
+		GateAmount:   3,
+		Timeout:      50 * time.Millisecond,
+	})
+	defer ff.Close()
+
+	ch, err := ff.GetBatchInfoChan()
+	if err != nil {
+		t.Fatalf("could not get batch info channel: %v", err)
+	}
+
+	start := time.Now()
+	_ = ff.Push(1, 2, 3, 4, 5)
+
+	select {
+	case batch := <-ch:
+		if !reflect.DeepEqual(batch.Items, []int{1, 2, 3}) {
+			t.Fatalf("expected: %v; got %v", []int{1, 2, 3}, batch.Items)
+		}
+		if batch.Info.Reason != flashflood.ReasonGate || batch.Info.Seq != 1 || batch.Info.Size != 3 {
+			t.Fatalf("expected: gate batch 1 of 3; got %+v", batch.Info)
+		}
+		if batch.Info.FirstPush.Before(start) || batch.Info.LastPush.Before(batch.Info.FirstPush) {
+			t.Fatalf("expected: push times after %v; got %+v", start, batch.Info)
+		}
+	default:
+		t.Fatalf("expected: gate batch; got nothing")
+	}
+
+	select {
+	case batch := <-ch:
+		if !reflect.DeepEqual(batch.Items, []int{4, 5}) {
+			t.Fatalf("expected: %v; got %v", []int{4, 5}, batch.Items)
+		}
+		if batch.Info.Reason != flashflood.ReasonTimeout || batch.Info.Seq != 2 || batch.Info.Size != 2 {
+			t.Fatalf("expected: timeout batch 2 of 2; got %+v", batch.Info)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatalf("expected: timeout batch; got nothing")
+	}
+
+	_ = ff.Push(6)
+	_ = ff.GetOnChan(1)
+	_ = ff.Push(7)
+	_, _ = ff.Drain(true, false)
+
+	for _, reason := range []flashflood.FlushReason{flashflood.ReasonGetOnChan, flashflood.ReasonDrain} {
+		select {
+		case batch := <-ch:
+			if batch.Info.Reason != reason {
+				t.Fatalf("expected: %v; got %v", reason, batch.Info.Reason)
+			}
+		default:
+			t.Fatalf("expected: %v batch; got nothing", reason)
+		}
+	}
+}
+
+func TestGetBatchInfoChanOutputMode(t *testing.T) {
+	ff := flashflood.New[int](&flashflood.Opts{})
+	defer ff.Close()
+
+	if _, err := ff.GetBatchChan(); err != nil {
+		t.Fatalf("could not get batch channel: %v", err)
+	}
+	if _, err := ff.GetBatchInfoChan(); !errors.Is(err, flashflood.ErrOutputMode) {
+		t.Fatalf("expected: %v; got %v", flashflood.ErrOutputMode, err)
+	}
+}
+
+func TestAddFuncInfo(t *testing.T) {
+	ff := flashflood.New[int](&flashflood.Opts{
+		BufferAmount: 10,
+		Timeout:      time.Second,
+	})
+	defer ff.Close()
+
+	ff.SetSizer(func(int) int { return 4 })
+
+	var infos []flashflood.BatchInfo
+	ff.AddFuncInfo(func(objs []int, info flashflood.BatchInfo, ff *flashflood.FlashFlood[int]) []int {
+		infos = append(infos, info)
+		return objs
+	})
+
+	_ = ff.Push(1, 2, 3)
+	if _, err := ff.Get(2); err != nil {
+		t.Fatalf("could not get: %v", err)
+	}
+	if _, err := ff.Drain(false, false); err != nil {
+		t.Fatalf("could not drain: %v", err)
+	}
+
+	expected := []struct {
+		reason flashflood.FlushReason
+		size   int
+	}{
+		{flashflood.ReasonGet, 2},
+		{flashflood.ReasonDrain, 1},
+	}
+	if len(infos) != len(expected) {
+		t.Fatalf("expected: %d batches; got %+v", len(expected), infos)
+	}
+	for n, e := range expected {
+		info := infos[n]
+		if info.Reason != e.reason || info.Size != e.size || info.Bytes != e.size*4 || info.Seq != uint64(n+1) {
+			t.Fatalf("expected: %v batch %d of %d; got %+v", e.reason, n+1, e.size, info)
+		}
+	}
+}
+
+func TestFlushReasonString(t *testing.T) {
+	if s := flashflood.ReasonFlushTimeout.String(); s != "flush-timeout" {
+		t.Fatalf("expected: flush-timeout; got %v", s)
+	}
+	if s := flashflood.FlushReason(-1).String(); s != "unknown" {
+		t.Fatalf("expected: unknown; got %v", s)
+	}
+}
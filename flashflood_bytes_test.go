@@ -0,0 +1,101 @@
+package flashflood_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/thisisdevelopment/flashflood/v2"
+)
+
+func stringLen(s string) int {
+	return len(s)
+}
+
+func TestGateBytes(t *testing.T) {
+	ff := flashflood.New[string](&flashflood.Opts{
+		BufferAmount: 100,
+		GateBytes:    10,
+		Timeout:      time.Second,
+	})
+	defer ff.Close()
+	ff.SetSizer(stringLen)
+
+	ch, _ := ff.GetBatchChan()
+
+	_ = ff.Push("aaaa", "bbbb")
+	select {
+	case batch := <-ch:
+		t.Fatalf("expected: nothing below 10 bytes; got %v", batch)
+	default:
+	}
+
+	_ = ff.Push("ccc", "dd")
+	select {
+	case batch := <-ch:
+		expected := []string{"aaaa", "bbbb", "ccc"}
+		if !reflect.DeepEqual(batch, expected) {
+			t.Fatalf("expected: %v; got %v", expected, batch)
+		}
+	default:
+		t.Fatalf("expected: batch at 10 bytes; got nothing")
+	}
+
+	if ff.Count() != 1 {
+		t.Fatalf("expected 1 in buffer; got %v", ff.Count())
+	}
+}
+
+func TestMaxBatchBytes(t *testing.T) {
+	ff := flashflood.New[string](&flashflood.Opts{
+		BufferAmount:  100,
+		MaxBatchBytes: 8,
+		Timeout:       time.Second,
+	})
+	defer ff.Close()
+	ff.SetSizer(stringLen)
+
+	ch, _ := ff.GetBatchChan()
+
+	_ = ff.Push("aaaa", "bbbb", "cc", "dddddddddd", "e")
+	_, _ = ff.Drain(true, false)
+
+	expected := [][]string{{"aaaa", "bbbb"}, {"cc"}, {"dddddddddd"}, {"e"}}
+	for _, e := range expected {
+		select {
+		case batch := <-ch:
+			if !reflect.DeepEqual(batch, e) {
+				t.Fatalf("expected: %v; got %v", e, batch)
+			}
+		default:
+			t.Fatalf("expected: %v; got nothing", e)
+		}
+	}
+}
+
+func TestGateBytesWithGateAmount(t *testing.T) {
+	ff := flashflood.New[string](&flashflood.Opts{
+		BufferAmount: 100,
+		GateAmount:   2,
+		GateBytes:    100,
+		Timeout:      50 * time.Millisecond,
+	})
+	defer ff.Close()
+	ff.SetSizer(stringLen)
+
+	ch, _ := ff.GetBatchChan()
+	_ = ff.Push("a", "b", "c")
+
+	// below GateBytes nothing is released on push, the timeout flushes per GateAmount
+	expected := [][]string{{"a", "b"}, {"c"}}
+	for _, e := range expected {
+		select {
+		case batch := <-ch:
+			if !reflect.DeepEqual(batch, e) {
+				t.Fatalf("expected: %v; got %v", e, batch)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected: %v; got nothing", e)
+		}
+	}
+}
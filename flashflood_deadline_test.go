@@ -0,0 +1,104 @@
+package flashflood_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/thisisdevelopment/flashflood/v2"
+)
+
+func TestDeadlineOrder(t *testing.T) {
+	ff := flashflood.New[string](&flashflood.Opts{
+		Timeout:         time.Hour,
+		DeadlineOrdered: true,
+	})
+	defer ff.Close()
+
+	now := time.Now()
+	_ = ff.PushDeadline(now.Add(3*time.Second), "c")
+	_ = ff.PushDeadline(now.Add(time.Second), "a")
+	_ = ff.Push("z") // due after Timeout
+	_ = ff.PushDeadline(now.Add(2*time.Second), "b1", "b2")
+
+	objs, _ := ff.Drain(false, false)
+	expected := []string{"a", "b1", "b2", "c", "z"}
+	if !reflect.DeepEqual(objs, expected) {
+		t.Fatalf("expected: %v; got %v", expected, objs)
+	}
+}
+
+func TestDeadlineFlushBeforeGate(t *testing.T) {
+	ff := flashflood.New[string](&flashflood.Opts{
+		GateAmount:      10,
+		Timeout:         time.Hour,
+		DeadlineOrdered: true,
+	})
+	defer ff.Close()
+	ch, _ := ff.GetBatchInfoChan()
+
+	now := time.Now()
+	_ = ff.PushDeadline(now.Add(time.Hour), "later")
+	_ = ff.PushDeadline(now.Add(30*time.Millisecond), "soon")
+
+	select {
+	case batch := <-ch:
+		if !reflect.DeepEqual(batch.Items, []string{"soon"}) || batch.Info.Reason != flashflood.ReasonDeadline {
+			t.Fatalf("expected: [soon] due; got %v %v", batch.Items, batch.Info.Reason)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected: due batch; got nothing")
+	}
+	if ff.Count() != 1 {
+		t.Fatalf("expected 1 in buffer; got %v", ff.Count())
+	}
+}
+
+func TestDeadlineFuncSlack(t *testing.T) {
+	ff := flashflood.New[time.Time](&flashflood.Opts{
+		Timeout:         time.Hour,
+		DeadlineOrdered: true,
+		DeadlineSlack:   150 * time.Millisecond,
+	})
+	defer ff.Close()
+	ff.SetDeadlineFunc(func(deadline time.Time) time.Time { return deadline })
+	ch, _ := ff.GetChan()
+
+	start := time.Now()
+	_ = ff.Push(start.Add(200 * time.Millisecond))
+
+	select {
+	case <-ch:
+		if elapsed := time.Since(start); elapsed >= 150*time.Millisecond {
+			t.Fatalf("expected: flush within the slack; got after %v", elapsed)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected: due element; got nothing")
+	}
+}
+
+func TestDeadlineIgnoresPing(t *testing.T) {
+	ff := flashflood.New[string](&flashflood.Opts{
+		Timeout:         30 * time.Millisecond,
+		DeadlineOrdered: true,
+	})
+	defer ff.Close()
+	ch, _ := ff.GetChan()
+
+	_ = ff.Push("x")
+	// the element times out on its own, activity does not postpone it
+	deadline := time.After(time.Second)
+	for {
+		ff.Ping()
+		select {
+		case v := <-ch:
+			if v != "x" {
+				t.Fatalf("expected: x; got %v", v)
+			}
+			return
+		case <-deadline:
+			t.Fatalf("expected: element after its deadline; got nothing")
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package flashflood_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/thisisdevelopment/flashflood/v2"
+)
+
+func TestPushAfterHeldBack(t *testing.T) {
+	ff := flashflood.New[string](&flashflood.Opts{
+		BufferAmount: 1,
+		Timeout:      time.Hour,
+	})
+	defer ff.Close()
+	ch, _ := ff.GetChan()
+
+	start := time.Now()
+	_ = ff.PushAfter(60*time.Millisecond, "retry:2")
+	_ = ff.PushAfter(30*time.Millisecond, "retry:1")
+	if st := ff.Stats(); st.Delayed != 2 || st.Buffered != 0 {
+		t.Fatalf("expected: 2 delayed; got %+v", st)
+	}
+	// not eligible for a flush yet
+	if objs, _ := ff.Drain(false, false); len(objs) != 0 {
+		t.Fatalf("expected: nothing to drain; got %v", objs)
+	}
+
+	// once due they are pushed, the ring of 1 releases the first when the second arrives
+	select {
+	case v := <-ch:
+		if v != "retry:1" || time.Since(start) < 60*time.Millisecond {
+			t.Fatalf("expected: retry:1 after 60ms; got %v after %v", v, time.Since(start))
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected: retry:1; got nothing")
+	}
+	if objs, _ := ff.Get(1); !reflect.DeepEqual(objs, []string{"retry:2"}) {
+		t.Fatalf("expected: %v; got %v", []string{"retry:2"}, objs)
+	}
+}
+
+func TestPushAtTimeout(t *testing.T) {
+	scheduler := flashflood.NewScheduler()
+	defer scheduler.Close()
+	ff := flashflood.New[string](&flashflood.Opts{
+		GateAmount: 10,
+		Timeout:    20 * time.Millisecond,
+		Scheduler:  scheduler,
+	})
+	defer ff.Close()
+	ch, _ := ff.GetBatchInfoChan()
+
+	start := time.Now()
+	_ = ff.PushAt(start.Add(30*time.Millisecond), "reminder")
+
+	// the Timeout of the buffer starts when the element is due
+	select {
+	case batch := <-ch:
+		if !reflect.DeepEqual(batch.Items, []string{"reminder"}) || batch.Info.Reason != flashflood.ReasonTimeout {
+			t.Fatalf("expected: [reminder] timed out; got %v %v", batch.Items, batch.Info.Reason)
+		}
+		if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+			t.Fatalf("expected: flush after 50ms; got %v", elapsed)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected: timeout batch; got nothing")
+	}
+}
+
+func TestPushAtShutdown(t *testing.T) {
+	ff := flashflood.New[string](&flashflood.Opts{
+		Timeout:         time.Hour,
+		MaxBufferAmount: 2,
+	})
+	ch, _ := ff.GetChan()
+
+	_ = ff.PushAfter(time.Hour, "a", "b")
+	// held back elements count towards MaxBufferAmount
+	if err := ff.TryPush("c"); !errors.Is(err, flashflood.ErrFull) {
+		t.Fatalf("expected: %v; got %v", flashflood.ErrFull, err)
+	}
+
+	if err := ff.Shutdown(context.Background()); err != nil {
+		t.Fatalf("could not shutdown: %v", err)
+	}
+	var got []string
+	for v := range ch {
+		got = append(got, v)
+	}
+	if !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Fatalf("expected: %v; got %v", []string{"a", "b"}, got)
+	}
+}
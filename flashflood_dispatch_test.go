@@ -0,0 +1,192 @@
+package flashflood_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/thisisdevelopment/flashflood/v2"
+)
+
+// slowFunc simulates an expensive transformation like compression or encoding
+func slowFunc(d time.Duration) flashflood.FuncStack[int] {
+	return func(objs []int, ff *flashflood.FlashFlood[int]) []int {
+		time.Sleep(d)
+		return objs
+	}
+}
+
+func TestAsyncDispatchOrder(t *testing.T) {
+	ff := flashflood.New[int](&flashflood.Opts{
+		BufferAmount:  1,
+		Timeout:       10 * time.Millisecond,
+		AsyncDispatch: true,
+	})
+	defer ff.Close()
+
+	ff.AddFunc(slowFunc(100 * time.Microsecond))
+	ch, _ := ff.GetChan()
+
+	amount := 100
+	for n := 0; n < amount; n++ {
+		_ = ff.Push(n)
+	}
+
+	for n := 0; n < amount; n++ {
+		select {
+		case v := <-ch:
+			if v != n {
+				t.Fatalf("expected: %d; got %d", n, v)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected: %d; got nothing", n)
+		}
+	}
+}
+
+func TestAsyncDispatchRetainOrder(t *testing.T) {
+	ff := flashflood.New[int](&flashflood.Opts{
+		BufferAmount:  1,
+		GateAmount:    2,
+		Timeout:       20 * time.Millisecond,
+		AsyncDispatch: true,
+	})
+	defer ff.Close()
+
+	// the first batch fails after the next batches were queued behind it
+	var calls atomic.Int64
+	ff.AddFuncE(func(objs []int, _ *flashflood.FlashFlood[int]) ([]int, error) {
+		if calls.Add(1) == 1 {
+			time.Sleep(20 * time.Millisecond)
+			return nil, errors.New("unavailable")
+		}
+		return objs, nil
+	})
+	ch, _ := ff.GetBatchChan()
+
+	for n := 1; n <= 7; n++ {
+		_ = ff.Push(n)
+	}
+
+	expected := [][]int{{1, 2}, {3, 4}, {5, 6}, {7}}
+	var got [][]int
+	for range expected {
+		select {
+		case batch := <-ch:
+			got = append(got, batch)
+		case <-time.After(time.Second):
+			t.Fatalf("expected: %v; got %v", expected, got)
+		}
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected: %v; got %v", expected, got)
+	}
+}
+
+func TestAsyncDispatchPushNotBlocked(t *testing.T) {
+	ff := flashflood.New[int](&flashflood.Opts{
+		BufferAmount:  1,
+		Timeout:       time.Second,
+		AsyncDispatch: true,
+	})
+	defer ff.Close()
+
+	ff.AddFunc(slowFunc(200 * time.Millisecond))
+	ch, _ := ff.GetChan()
+
+	start := time.Now()
+	_ = ff.Push(1, 2, 3)
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Fatalf("expected: push without waiting for the FuncStack; got %v", elapsed)
+	}
+
+	select {
+	case v := <-ch:
+		if v != 1 {
+			t.Fatalf("expected: %d; got %d", 1, v)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected: dispatched element; got nothing")
+	}
+}
+
+func TestAsyncDispatchShutdown(t *testing.T) {
+	ff := flashflood.New[int](&flashflood.Opts{
+		BufferAmount:  2,
+		Timeout:       time.Second,
+		AsyncDispatch: true,
+	})
+
+	ff.AddFunc(slowFunc(10 * time.Millisecond))
+	ch, _ := ff.GetChan()
+	_ = ff.Push(1, 2, 3, 4, 5)
+
+	if err := ff.Shutdown(context.Background()); err != nil {
+		t.Fatalf("could not shutdown: %v", err)
+	}
+
+	drained := []int{}
+	for v := range ch {
+		drained = append(drained, v)
+	}
+	if fmt.Sprint(drained) != "[1 2 3 4 5]" {
+		t.Fatalf("expected: %v; got %v", []int{1, 2, 3, 4, 5}, drained)
+	}
+}
+
+func TestAsyncDispatchMaxBufferAmount(t *testing.T) {
+	ff := flashflood.New[int](&flashflood.Opts{
+		BufferAmount:    1,
+		MaxBufferAmount: 3,
+		Timeout:         time.Second,
+		ChannelBuffer:   1,
+		AsyncDispatch:   true,
+	})
+	defer ff.Close()
+
+	_, _ = ff.GetChan()
+
+	// the batch of 1 and 2 is dispatched, 1 fits on the channel, 2 blocks the dispatcher and 3 stays in the buffer
+	_ = ff.Push(1, 2, 3)
+	time.Sleep(10 * time.Millisecond)
+
+	if st := ff.Stats(); st.Queued != 2 || st.Buffered != 1 {
+		t.Fatalf("expected: 2 queued and 1 buffered; got %+v", st)
+	}
+	if err := ff.TryPush(4); err == nil {
+		t.Fatalf("expected: queued elements to count towards MaxBufferAmount; got nil")
+	}
+}
+
+func BenchmarkPushChanCBFuncDispatch(b *testing.B) {
+	for _, async := range []bool{false, true} {
+		b.Run(fmt.Sprintf("async/%v", async), func(b *testing.B) {
+			ff := flashflood.New[int](&flashflood.Opts{
+				Timeout:       10 * time.Millisecond,
+				BufferAmount:  512,
+				GateAmount:    16,
+				AsyncDispatch: async,
+			})
+			defer ff.Close()
+
+			ff.AddFunc(slowFunc(10 * time.Microsecond))
+			ch, _ := ff.GetChan()
+			go func() {
+				for range ch {
+				}
+			}()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					_ = ff.Push(1)
+				}
+			})
+		})
+	}
+}
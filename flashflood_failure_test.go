@@ -0,0 +1,179 @@
+package flashflood_test
+
+import (
+	"errors"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/thisisdevelopment/flashflood/v2"
+)
+
+var errSink = errors.New("sink unavailable")
+
+// failFirst returns a FuncStackE failing the first n batches
+func failFirst(n int32) flashflood.FuncStackE[int] {
+	calls := &atomic.Int32{}
+	return func(objs []int, ff *flashflood.FlashFlood[int]) ([]int, error) {
+		if calls.Add(1) <= n {
+			return nil, errSink
+		}
+		return objs, nil
+	}
+}
+
+func TestFailureRetainRetries(t *testing.T) {
+	ff := flashflood.New[int](&flashflood.Opts{
+		BufferAmount: 10,
+		Timeout:      30 * time.Millisecond,
+	})
+	defer ff.Close()
+
+	ff.AddFuncE(failFirst(1))
+	ch, _ := ff.GetBatchChan()
+
+	_ = ff.Push(1, 2)
+
+	select {
+	case err := <-ff.Errors():
+		if !errors.Is(err, flashflood.ErrFuncStack) || !errors.Is(err, errSink) {
+			t.Fatalf("expected: %v wrapping %v; got %v", flashflood.ErrFuncStack, errSink, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected: error of the timeout flush; got nothing")
+	}
+
+	select {
+	case batch := <-ch:
+		if !reflect.DeepEqual(batch, []int{1, 2}) {
+			t.Fatalf("expected: %v; got %v", []int{1, 2}, batch)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected: retried batch; got nothing")
+	}
+
+	if st := ff.Stats(); st.Failed != 1 {
+		t.Fatalf("expected 1 failed batch; got %+v", st)
+	}
+}
+
+func TestFailureRetainGet(t *testing.T) {
+	ff := flashflood.New[int](&flashflood.Opts{
+		BufferAmount: 10,
+		Timeout:      time.Second,
+	})
+	defer ff.Close()
+
+	ff.AddFuncE(failFirst(1))
+	_ = ff.Push(1, 2, 3)
+
+	if _, err := ff.Get(2); !errors.Is(err, errSink) {
+		t.Fatalf("expected: %v; got %v", errSink, err)
+	}
+	if ff.Count() != 3 {
+		t.Fatalf("expected 3 in buffer; got %v", ff.Count())
+	}
+
+	objs, err := ff.Get(2)
+	if err != nil {
+		t.Fatalf("could not get: %v", err)
+	}
+	if !reflect.DeepEqual(objs, []int{1, 2}) {
+		t.Fatalf("expected: %v; got %v", []int{1, 2}, objs)
+	}
+}
+
+func TestFailureDeadLetter(t *testing.T) {
+	ff := flashflood.New[int](&flashflood.Opts{
+		BufferAmount:  10,
+		Timeout:       time.Second,
+		FailurePolicy: flashflood.FailureDeadLetter,
+	})
+	defer ff.Close()
+
+	var dead []int
+	var reason flashflood.FlushReason
+	ff.OnDeadLetter(func(objs []int, info flashflood.BatchInfo, err error) {
+		dead = append(dead, objs...)
+		reason = info.Reason
+	})
+	ff.AddFuncE(failFirst(1))
+	_, _ = ff.GetChan()
+
+	_ = ff.Push(1, 2, 3)
+	if _, err := ff.Drain(true, false); !errors.Is(err, errSink) {
+		t.Fatalf("expected: %v; got %v", errSink, err)
+	}
+
+	if !reflect.DeepEqual(dead, []int{1, 2, 3}) || reason != flashflood.ReasonDrain {
+		t.Fatalf("expected: %v from drain; got %v from %v", []int{1, 2, 3}, dead, reason)
+	}
+	if ff.Count() != 0 {
+		t.Fatalf("expected empty buffer; got %v", ff.Count())
+	}
+}
+
+func TestFailureDropOnError(t *testing.T) {
+	errs := make(chan error, 1)
+	ff := flashflood.New[int](&flashflood.Opts{
+		BufferAmount:  2,
+		Timeout:       time.Second,
+		FailurePolicy: flashflood.FailureDrop,
+		OnError:       func(err error) { errs <- err },
+	})
+	defer ff.Close()
+
+	ff.AddFuncE(failFirst(1))
+	_, _ = ff.GetChan()
+
+	// the ring overflow of Push fails asynchronously, Push itself succeeds
+	if err := ff.Push(1, 2, 3); err != nil {
+		t.Fatalf("could not push: %v", err)
+	}
+
+	select {
+	case err := <-errs:
+		if !errors.Is(err, errSink) {
+			t.Fatalf("expected: %v; got %v", errSink, err)
+		}
+	default:
+		t.Fatalf("expected: OnError to be called; got nothing")
+	}
+
+	if ff.Count() != 2 {
+		t.Fatalf("expected 2 in buffer; got %v", ff.Count())
+	}
+}
+
+func TestOnErrorCallsInstance(t *testing.T) {
+	counts := make(chan uint64, 1)
+	var ff *flashflood.FlashFlood[int]
+	ff = flashflood.New[int](&flashflood.Opts{
+		BufferAmount:  2,
+		Timeout:       time.Second,
+		FailurePolicy: flashflood.FailureDrop,
+		// called once the buffer is unlocked, so calling the instance does not deadlock
+		OnError: func(err error) { counts <- ff.Count() },
+	})
+	defer ff.Close()
+
+	ff.AddFuncE(failFirst(1))
+	_, _ = ff.GetChan()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- ff.Push(1, 2, 3)
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("could not push: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected: Push to return; got a deadlock")
+	}
+	if cnt := <-counts; cnt != 2 {
+		t.Fatalf("expected 2 in buffer; got %v", cnt)
+	}
+}
@@ -0,0 +1,125 @@
+package flashflood_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/thisisdevelopment/flashflood/v2"
+)
+
+func TestFairnessRoundRobin(t *testing.T) {
+	ff := flashflood.New[string](&flashflood.Opts{
+		Timeout:  time.Second,
+		Fairness: flashflood.FairnessRoundRobin,
+	})
+	defer ff.Close()
+	ff.SetFairnessKey(tenantOf)
+
+	_ = ff.Push("a:1", "a:2", "a:3", "a:4", "b:1", "b:2", "c:1")
+
+	objs, _ := ff.Drain(false, false)
+	expected := []string{"a:1", "b:1", "c:1", "a:2", "b:2", "a:3", "a:4"}
+	if !reflect.DeepEqual(objs, expected) {
+		t.Fatalf("expected: %v; got %v", expected, objs)
+	}
+}
+
+func TestFairnessWeighted(t *testing.T) {
+	ff := flashflood.New[string](&flashflood.Opts{
+		Timeout:  time.Second,
+		Fairness: flashflood.FairnessWeighted,
+	})
+	defer ff.Close()
+	ff.SetFairnessKey(tenantOf)
+	ff.SetFairnessWeight(func(key string) int {
+		if key == "a" {
+			return 2
+		}
+		return 1
+	})
+
+	_ = ff.Push("b:1", "b:2", "b:3", "a:1", "a:2", "a:3", "a:4")
+
+	objs, _ := ff.Drain(false, false)
+	expected := []string{"b:1", "a:1", "a:2", "b:2", "a:3", "a:4", "b:3"}
+	if !reflect.DeepEqual(objs, expected) {
+		t.Fatalf("expected: %v; got %v", expected, objs)
+	}
+}
+
+func TestFairnessGateBatches(t *testing.T) {
+	ff := flashflood.New[string](&flashflood.Opts{
+		BufferAmount: 1,
+		GateAmount:   2,
+		Timeout:      time.Second,
+		Fairness:     flashflood.FairnessRoundRobin,
+	})
+	defer ff.Close()
+	ff.SetFairnessKey(tenantOf)
+	ch, _ := ff.GetBatchInfoChan()
+
+	// the flooding tenant does not delay b
+	_ = ff.Push("a:1", "a:2", "a:3", "a:4", "b:1")
+
+	select {
+	case batch := <-ch:
+		if !reflect.DeepEqual(batch.Items, []string{"a:1", "b:1"}) {
+			t.Fatalf("expected: %v; got %v", []string{"a:1", "b:1"}, batch.Items)
+		}
+		if batch.Info.FirstPush.After(batch.Info.LastPush) {
+			t.Fatalf("expected: first push before last push; got %v after %v", batch.Info.FirstPush, batch.Info.LastPush)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected: gate batch; got nothing")
+	}
+}
+
+func TestFairnessKeyQuota(t *testing.T) {
+	ff := flashflood.New[string](&flashflood.Opts{
+		Timeout:  time.Second,
+		Fairness: flashflood.FairnessRoundRobin,
+		KeyQuota: 2,
+	})
+	defer ff.Close()
+	ff.SetFairnessKey(tenantOf)
+
+	if err := ff.TryPush("a:1", "a:2"); err != nil {
+		t.Fatalf("could not push: %v", err)
+	}
+	if err := ff.TryPush("a:3"); !errors.Is(err, flashflood.ErrFull) {
+		t.Fatalf("expected: %v; got %v", flashflood.ErrFull, err)
+	}
+	if err := ff.TryPush("b:1"); err != nil {
+		t.Fatalf("expected: room for another key; got %v", err)
+	}
+	// can never fit
+	if err := ff.Push("c:1", "c:2", "c:3"); !errors.Is(err, flashflood.ErrFull) {
+		t.Fatalf("expected: %v; got %v", flashflood.ErrFull, err)
+	}
+
+	// a blocked push continues once the key has room
+	done := make(chan error)
+	go func() {
+		done <- ff.Push("a:3")
+	}()
+	select {
+	case err := <-done:
+		t.Fatalf("expected: blocking push; got %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	objs, _ := ff.Get(1)
+	if len(objs) != 1 || !strings.HasPrefix(objs[0], "a:") {
+		t.Fatalf("expected: element of a; got %v", objs)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("could not push: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected: push to continue; got blocked")
+	}
+}
@@ -0,0 +1,187 @@
+package flashflood_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/thisisdevelopment/flashflood/v2"
+)
+
+func TestFlushWaitsForConsumer(t *testing.T) {
+	ff := flashflood.New[int](&flashflood.Opts{
+		BufferAmount:  10,
+		Timeout:       time.Hour,
+		ChannelBuffer: 10,
+	})
+	defer ff.Close()
+	ch, _ := ff.GetChan()
+
+	_ = ff.Push(1, 2, 3, 4, 5)
+	var received atomic.Int64
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		for range ch {
+			received.Add(1)
+		}
+	}()
+
+	start := time.Now()
+	if err := ff.Flush(context.Background()); err != nil {
+		t.Fatalf("could not flush: %v", err)
+	}
+	// the consumer took every element off the channel
+	if len(ch) != 0 {
+		t.Fatalf("expected: 0 on the channel; got %v", len(ch))
+	}
+	// Drain would have returned right away, the channel buffer has room for all elements
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Fatalf("expected: to wait for the consumer; got %v", elapsed)
+	}
+	if ff.Count() != 0 {
+		t.Fatalf("expected 0 in buffer; got %v", ff.Count())
+	}
+	waitReceived(t, &received, 5)
+}
+
+// waitReceived waits until the consumer handled the elements it received
+func waitReceived(t *testing.T, received *atomic.Int64, expected int64) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for received.Load() != expected && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if received.Load() != expected {
+		t.Fatalf("expected: %v received; got %v", expected, received.Load())
+	}
+}
+
+func TestFlushContext(t *testing.T) {
+	ff := flashflood.New[int](&flashflood.Opts{
+		BufferAmount:  10,
+		Timeout:       time.Hour,
+		ChannelBuffer: 10,
+	})
+	defer ff.Close()
+	ch, _ := ff.GetBatchInfoChan()
+
+	_ = ff.Push(1, 2, 3)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := ff.Flush(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected: %v; got %v", context.DeadlineExceeded, err)
+	}
+	// the elements were flushed, only nobody received them
+	if len(ch) != 1 {
+		t.Fatalf("expected: 1 batch on the channel; got %v", len(ch))
+	}
+	if batch := <-ch; len(batch.Items) != 3 || batch.Info.Reason != flashflood.ReasonFlush {
+		t.Fatalf("expected: 3 items flush; got %v %v", batch.Items, batch.Info.Reason)
+	}
+}
+
+func TestFlushErrors(t *testing.T) {
+	ff := flashflood.New[int](&flashflood.Opts{})
+	_ = ff.Push(1)
+	if err := ff.Flush(context.Background()); !errors.Is(err, flashflood.ErrNotFetched) {
+		t.Fatalf("expected: %v; got %v", flashflood.ErrNotFetched, err)
+	}
+	if ff.Count() != 1 {
+		t.Fatalf("expected 1 in buffer; got %v", ff.Count())
+	}
+
+	ff.Close()
+	if err := ff.Flush(context.Background()); !errors.Is(err, flashflood.ErrClosed) {
+		t.Fatalf("expected: %v; got %v", flashflood.ErrClosed, err)
+	}
+}
+
+func TestFlushAsyncDispatch(t *testing.T) {
+	ff := flashflood.New[int](&flashflood.Opts{
+		BufferAmount:  10,
+		GateAmount:    2,
+		Timeout:       time.Hour,
+		ChannelBuffer: 10,
+		AsyncDispatch: true,
+	})
+	defer ff.Close()
+	ch, _ := ff.GetBatchInfoChan()
+	ff.AddFunc(func(objs []int, _ *flashflood.FlashFlood[int]) []int {
+		time.Sleep(10 * time.Millisecond)
+		return objs
+	})
+
+	_ = ff.Push(1, 2, 3)
+	var received atomic.Int64
+	go func() {
+		for batch := range ch {
+			received.Add(int64(len(batch.Items)))
+		}
+	}()
+
+	// the dispatcher is still running the FuncStack when Flush starts waiting
+	if err := ff.Flush(context.Background()); err != nil {
+		t.Fatalf("could not flush: %v", err)
+	}
+	if st := ff.Stats(); st.Queued != 0 || len(ch) != 0 {
+		t.Fatalf("expected: nothing queued or on the channel; got %+v %v", st, len(ch))
+	}
+	waitReceived(t, &received, 3)
+}
+
+func TestShardedFlush(t *testing.T) {
+	ff := flashflood.NewSharded[int](4, &flashflood.Opts{
+		BufferAmount:  10,
+		Timeout:       time.Hour,
+		ChannelBuffer: 100,
+	})
+	defer ff.Close()
+	ch, _ := ff.GetChan()
+
+	for n := 0; n < 20; n++ {
+		_ = ff.Push(n)
+	}
+	var received atomic.Int64
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		for range ch {
+			received.Add(1)
+		}
+	}()
+
+	if err := ff.Flush(context.Background()); err != nil {
+		t.Fatalf("could not flush: %v", err)
+	}
+	if len(ch) != 0 {
+		t.Fatalf("expected: 0 on the channel; got %v", len(ch))
+	}
+	waitReceived(t, &received, 20)
+}
+
+func TestKeyedFlush(t *testing.T) {
+	ff := flashflood.NewKeyed[string, string](&flashflood.Opts{
+		Timeout:       time.Hour,
+		ChannelBuffer: 10,
+	}, tenantOf)
+	defer ff.Close()
+	ch, _ := ff.GetChan()
+
+	_ = ff.Push("a:1", "b:1", "a:2")
+	var received atomic.Int64
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		for batch := range ch {
+			received.Add(int64(len(batch.Items)))
+		}
+	}()
+
+	if err := ff.Flush(context.Background()); err != nil {
+		t.Fatalf("could not flush: %v", err)
+	}
+	if len(ch) != 0 {
+		t.Fatalf("expected: 0 on the channel; got %v", len(ch))
+	}
+	waitReceived(t, &received, 3)
+}
@@ -2,6 +2,7 @@ package flashflood
 
 import (
 	"testing"
+	"time"
 )
 
 func TestDefaultOpts(t *testing.T) {
@@ -28,3 +29,22 @@ func TestChannelFetchedStatus(t *testing.T) {
 		t.Fatalf("expected: true got false")
 	}
 }
+
+func TestNextWakeIdle(t *testing.T) {
+	ff := New[string](&Opts{
+		BufferAmount: 3,
+		Timeout:      50 * time.Millisecond,
+	})
+	defer ff.Close()
+
+	if _, ok := ff.nextWake(time.Now()); ok {
+		t.Fatalf("expected: no timer for an empty buffer; got armed timer")
+	}
+
+	_ = ff.Push("a")
+
+	wait, ok := ff.nextWake(time.Now())
+	if !ok || wait <= 0 || wait > 50*time.Millisecond {
+		t.Fatalf("expected: timer armed within %v; got %v %v", 50*time.Millisecond, wait, ok)
+	}
+}
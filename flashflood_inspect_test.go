@@ -0,0 +1,74 @@
+package flashflood_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/thisisdevelopment/flashflood/v2"
+)
+
+func TestPeekSnapshotRange(t *testing.T) {
+	ff := flashflood.New[string](&flashflood.Opts{
+		Timeout:        50 * time.Millisecond,
+		PriorityLevels: 2,
+	})
+	defer ff.Close()
+	ch, _ := ff.GetChan()
+
+	_ = ff.Push("a", "b")
+	_ = ff.PushPriority(1, "urgent")
+	_ = ff.PushAfter(time.Hour, "later")
+
+	// in the order the elements leave the buffer, held back elements excluded
+	if objs := ff.Peek(2); !reflect.DeepEqual(objs, []string{"urgent", "a"}) {
+		t.Fatalf("expected: %v; got %v", []string{"urgent", "a"}, objs)
+	}
+	if objs := ff.Peek(10); len(objs) != 3 {
+		t.Fatalf("expected: 3 elements; got %v", objs)
+	}
+	if objs := ff.Snapshot(); !reflect.DeepEqual(objs, []string{"urgent", "a", "b"}) {
+		t.Fatalf("expected: %v; got %v", []string{"urgent", "a", "b"}, objs)
+	}
+
+	var ranged []string
+	ff.Range(func(k int, obj string) bool {
+		ranged = append(ranged, obj)
+		return k < 1
+	})
+	if !reflect.DeepEqual(ranged, []string{"urgent", "a"}) {
+		t.Fatalf("expected: %v; got %v", []string{"urgent", "a"}, ranged)
+	}
+	if ff.Count() != 3 {
+		t.Fatalf("expected 3 in buffer; got %v", ff.Count())
+	}
+
+	// inspecting is no activity, the timeout is not postponed
+	start := time.Now()
+	deadline := time.After(time.Second)
+	for {
+		_ = ff.Snapshot()
+		select {
+		case <-ch:
+			if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+				t.Fatalf("expected: timeout flush; got after %v", elapsed)
+			}
+			return
+		case <-deadline:
+			t.Fatalf("expected: timeout flush; got nothing")
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+}
+
+func TestPeekEmpty(t *testing.T) {
+	ff := flashflood.New[int](&flashflood.Opts{})
+	defer ff.Close()
+
+	if objs := ff.Peek(3); objs != nil {
+		t.Fatalf("expected: nil; got %v", objs)
+	}
+	if objs := ff.Snapshot(); objs != nil {
+		t.Fatalf("expected: nil; got %v", objs)
+	}
+}
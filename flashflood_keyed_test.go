@@ -0,0 +1,190 @@
+package flashflood_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/thisisdevelopment/flashflood/v2"
+)
+
+// tenantOf returns the tenant of "tenant:value" elements
+func tenantOf(s string) string {
+	return strings.SplitN(s, ":", 2)[0]
+}
+
+func TestKeyedGatePerKey(t *testing.T) {
+	ff := flashflood.NewKeyed[string, string](&flashflood.Opts{
+		BufferAmount: 1,
+		GateAmount:   2,
+		Timeout:      50 * time.Millisecond,
+	}, tenantOf)
+	defer ff.Close()
+
+	ch, _ := ff.GetChan()
+
+	_ = ff.Push("a:1", "b:1", "a:2", "b:2", "a:3", "b:3", "c:1")
+
+	batches := map[string][]string{}
+	for n := 0; n < 2; n++ {
+		select {
+		case batch := <-ch:
+			if batch.Info.Reason != flashflood.ReasonGate {
+				t.Fatalf("expected: %v; got %v", flashflood.ReasonGate, batch.Info.Reason)
+			}
+			batches[batch.Key] = batch.Items
+		case <-time.After(time.Second):
+			t.Fatalf("expected: gate batch; got nothing")
+		}
+	}
+	if !reflect.DeepEqual(batches["a"], []string{"a:1", "a:2"}) || !reflect.DeepEqual(batches["b"], []string{"b:1", "b:2"}) {
+		t.Fatalf("expected: homogeneous batches per key; got %v", batches)
+	}
+
+	// a:3, b:3 and c:1 time out in their own batches
+	for n := 0; n < 3; n++ {
+		select {
+		case batch := <-ch:
+			if len(batch.Items) != 1 || tenantOf(batch.Items[0]) != batch.Key || batch.Info.Reason != flashflood.ReasonTimeout {
+				t.Fatalf("expected: timeout batch of one element of its key; got %+v", batch)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected: timeout batch; got nothing")
+		}
+	}
+}
+
+func TestKeyedMaxKeys(t *testing.T) {
+	ff := flashflood.NewKeyed[string, string](&flashflood.Opts{
+		Timeout: time.Second,
+		MaxKeys: 2,
+	}, tenantOf)
+	defer ff.Close()
+
+	if err := ff.Push("a:1", "b:1", "a:2"); err != nil {
+		t.Fatalf("could not push: %v", err)
+	}
+	if err := ff.Push("c:1"); !errors.Is(err, flashflood.ErrMaxKeys) {
+		t.Fatalf("expected: %v; got %v", flashflood.ErrMaxKeys, err)
+	}
+	if st := ff.Stats(); st.Keys != 2 || st.Buffered != 3 {
+		t.Fatalf("expected: 2 keys and 3 buffered; got %+v", st)
+	}
+}
+
+func TestKeyedMaxKeysAfterFlush(t *testing.T) {
+	// without KeyIdleTimeout the flushed keys keep their empty buffers
+	ff := flashflood.NewKeyed[string, string](&flashflood.Opts{
+		Timeout: 10 * time.Millisecond,
+		MaxKeys: 1,
+	}, tenantOf)
+	defer ff.Close()
+	ch, _ := ff.GetChan()
+
+	for _, obj := range []string{"a:1", "b:1", "c:1"} {
+		if err := ff.Push(obj); err != nil {
+			t.Fatalf("could not push %v: %v", obj, err)
+		}
+		select {
+		case batch := <-ch:
+			if !reflect.DeepEqual(batch.Items, []string{obj}) {
+				t.Fatalf("expected: %v; got %v", []string{obj}, batch.Items)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected: %v; got nothing", obj)
+		}
+	}
+	if st := ff.Stats(); st.Keys != 1 || st.Buffered != 0 {
+		t.Fatalf("expected: 1 key and 0 buffered; got %+v", st)
+	}
+}
+
+func TestKeyedEvictIdle(t *testing.T) {
+	ff := flashflood.NewKeyed[string, string](&flashflood.Opts{
+		Timeout:        time.Second,
+		KeyIdleTimeout: 30 * time.Millisecond,
+		MaxKeys:        1,
+	}, tenantOf)
+	defer ff.Close()
+
+	ch, _ := ff.GetChan()
+	_ = ff.Push("a:1")
+
+	select {
+	case batch := <-ch:
+		if batch.Key != "a" || batch.Info.Reason != flashflood.ReasonEvict {
+			t.Fatalf("expected: evicted batch of a; got %+v", batch)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected: evicted batch; got nothing")
+	}
+
+	if st := ff.Stats(); st.Keys != 0 || st.Evicted != 1 {
+		t.Fatalf("expected: no keys and 1 evicted; got %+v", st)
+	}
+	// the evicted key made room for a new one
+	if err := ff.Push("b:1"); err != nil {
+		t.Fatalf("could not push: %v", err)
+	}
+}
+
+func TestKeyedShutdown(t *testing.T) {
+	ff := flashflood.NewKeyed[string, string](&flashflood.Opts{
+		Timeout: time.Second,
+	}, tenantOf)
+
+	ch, _ := ff.GetChan()
+	ff.AddFunc(func(objs []string, ff *flashflood.FlashFlood[string]) []string {
+		for n := range objs {
+			objs[n] = strings.ToUpper(objs[n])
+		}
+		return objs
+	})
+	_ = ff.Push("a:x", "b:y", "a:z")
+
+	if err := ff.Shutdown(context.Background()); err != nil {
+		t.Fatalf("could not shutdown: %v", err)
+	}
+
+	batches := map[string][]string{}
+	for batch := range ch {
+		batches[batch.Key] = batch.Items
+	}
+	expected := map[string][]string{"a": {"A:X", "A:Z"}, "b": {"B:Y"}}
+	if !reflect.DeepEqual(batches, expected) {
+		t.Fatalf("expected: %v; got %v", expected, batches)
+	}
+	if err := ff.Push("a:1"); !errors.Is(err, flashflood.ErrClosed) {
+		t.Fatalf("expected: %v; got %v", flashflood.ErrClosed, err)
+	}
+}
+
+func TestKeyedMemoryPerKey(t *testing.T) {
+	type record struct {
+		key     int
+		payload [88]byte
+	}
+	ff := flashflood.NewKeyed[int, record](&flashflood.Opts{}, func(r record) int { return r.key })
+	defer ff.Close()
+	_, _ = ff.GetChan()
+
+	const keys = 1000
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	for n := 0; n < keys; n++ {
+		_ = ff.Push(record{key: n})
+	}
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+
+	// the buffer of a key must not carry channels or a ring sized for the output
+	if perKey := (after.HeapAlloc - before.HeapAlloc) / keys; perKey > 4<<10 {
+		t.Fatalf("expected: at most %v bytes per key; got %v", 4<<10, perKey)
+	}
+	_ = ff.Drain()
+}
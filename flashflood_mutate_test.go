@@ -0,0 +1,96 @@
+package flashflood_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/thisisdevelopment/flashflood/v2"
+)
+
+func TestRemoveFunc(t *testing.T) {
+	ff := flashflood.New[string](&flashflood.Opts{
+		Timeout: time.Hour,
+	})
+	defer ff.Close()
+
+	_ = ff.Push("alice:1", "bob:1", "alice:2", "carol:1")
+	_ = ff.PushAfter(time.Hour, "alice:3", "bob:2")
+
+	// alice unsubscribed
+	removed := ff.RemoveFunc(func(obj string) bool { return tenantOf(obj) == "alice" })
+	if removed != 3 {
+		t.Fatalf("expected: 3 removed; got %d", removed)
+	}
+	if objs := ff.Snapshot(); !reflect.DeepEqual(objs, []string{"bob:1", "carol:1"}) {
+		t.Fatalf("expected: %v; got %v", []string{"bob:1", "carol:1"}, objs)
+	}
+	if st := ff.Stats(); st.Delayed != 1 {
+		t.Fatalf("expected: 1 delayed; got %d", st.Delayed)
+	}
+
+	removed = ff.RetainFunc(func(obj string) bool { return tenantOf(obj) == "bob" })
+	if removed != 1 {
+		t.Fatalf("expected: 1 removed; got %d", removed)
+	}
+	if objs := ff.Snapshot(); !reflect.DeepEqual(objs, []string{"bob:1"}) {
+		t.Fatalf("expected: %v; got %v", []string{"bob:1"}, objs)
+	}
+}
+
+func TestReplaceUpdateFunc(t *testing.T) {
+	ff := flashflood.New[string](&flashflood.Opts{
+		Timeout: time.Hour,
+	})
+	ch, _ := ff.GetChan()
+
+	_ = ff.Push("a:1", "b:1", "a:2")
+	if n := ff.Replace(func(obj string) bool { return obj == "b:1" }, "b:cancelled"); n != 1 {
+		t.Fatalf("expected: 1 replaced; got %d", n)
+	}
+	ff.UpdateFunc(strings.ToUpper)
+
+	if err := ff.Shutdown(context.Background()); err != nil {
+		t.Fatalf("could not shutdown: %v", err)
+	}
+	var got []string
+	for v := range ch {
+		got = append(got, v)
+	}
+	expected := []string{"A:1", "B:CANCELLED", "A:2"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected: %v; got %v", expected, got)
+	}
+}
+
+func TestRemoveFuncFreesRoom(t *testing.T) {
+	ff := flashflood.New[string](&flashflood.Opts{
+		Timeout:         time.Hour,
+		MaxBufferAmount: 2,
+		Fairness:        flashflood.FairnessRoundRobin,
+	})
+	defer ff.Close()
+	ff.SetFairnessKey(tenantOf)
+
+	_ = ff.Push("a:1", "b:1")
+	done := make(chan error)
+	go func() {
+		done <- ff.Push("b:2")
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	ff.RemoveFunc(func(obj string) bool { return obj == "a:1" })
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("could not push: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected: push to continue; got blocked")
+	}
+	if objs := ff.Snapshot(); !reflect.DeepEqual(objs, []string{"b:1", "b:2"}) {
+		t.Fatalf("expected: %v; got %v", []string{"b:1", "b:2"}, objs)
+	}
+}
@@ -0,0 +1,89 @@
+package flashflood_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/thisisdevelopment/flashflood/v2"
+)
+
+func collectInts(ch <-chan int) []int {
+	var r []int
+	for {
+		select {
+		case v := <-ch:
+			r = append(r, v)
+		default:
+			return r
+		}
+	}
+}
+
+func TestOverflowPolicies(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   flashflood.OverflowPolicy
+		received []int
+		dropped  []int
+	}{
+		{"drop newest", flashflood.OverflowDropNewest, []int{1, 2}, []int{3, 4}},
+		{"drop oldest", flashflood.OverflowDropOldest, []int{3, 4}, []int{1, 2}},
+		{"block with timeout", flashflood.OverflowBlockWithTimeout, []int{1, 2}, []int{3, 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ff := flashflood.New[int](&flashflood.Opts{
+				BufferAmount:    1,
+				ChannelBuffer:   2,
+				Timeout:         time.Second,
+				OverflowPolicy:  tt.policy,
+				OverflowTimeout: 10 * time.Millisecond,
+			})
+			defer ff.Close()
+
+			var dropped []int
+			ff.OnDrop(func(v int) {
+				dropped = append(dropped, v)
+			})
+
+			ch, _ := ff.GetChan()
+			for v := 1; v <= 5; v++ {
+				_ = ff.Push(v)
+			}
+
+			if received := collectInts(ch); !reflect.DeepEqual(received, tt.received) {
+				t.Fatalf("expected received: %v; got %v", tt.received, received)
+			}
+			if !reflect.DeepEqual(dropped, tt.dropped) {
+				t.Fatalf("expected dropped: %v; got %v", tt.dropped, dropped)
+			}
+			if s := ff.Stats(); s.Dropped != uint64(len(tt.dropped)) || s.Buffered != 1 {
+				t.Fatalf("expected: 2 dropped and 1 buffered; got %+v", s)
+			}
+		})
+	}
+}
+
+func TestOverflowDropNewestBatch(t *testing.T) {
+	ff := flashflood.New[int](&flashflood.Opts{
+		BufferAmount:   1,
+		GateAmount:     2,
+		ChannelBuffer:  1,
+		Timeout:        time.Second,
+		OverflowPolicy: flashflood.OverflowDropNewest,
+	})
+	defer ff.Close()
+
+	ch, _ := ff.GetBatchChan()
+	_ = ff.Push(1, 2, 3)
+	_ = ff.Push(4, 5)
+
+	if batch := <-ch; !reflect.DeepEqual(batch, []int{1, 2}) {
+		t.Fatalf("expected: %v; got %v", []int{1, 2}, batch)
+	}
+	if s := ff.Stats(); s.Dropped != 2 {
+		t.Fatalf("expected %d dropped; got %d", 2, s.Dropped)
+	}
+}
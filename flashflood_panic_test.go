@@ -0,0 +1,90 @@
+package flashflood_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/thisisdevelopment/flashflood/v2"
+)
+
+// panicOn returns a FuncStack panicking on batches holding v
+func panicOn(v int) flashflood.FuncStack[int] {
+	return func(objs []int, ff *flashflood.FlashFlood[int]) []int {
+		for _, obj := range objs {
+			if obj == v {
+				panic("poison element")
+			}
+		}
+		return objs
+	}
+}
+
+func TestPanicQuarantined(t *testing.T) {
+	ff := flashflood.New[int](&flashflood.Opts{
+		BufferAmount: 1,
+		Timeout:      time.Second,
+	})
+	defer ff.Close()
+
+	var dead []int
+	ff.OnDeadLetter(func(objs []int, info flashflood.BatchInfo, err error) {
+		dead = append(dead, objs...)
+	})
+	ff.AddFunc(panicOn(13))
+	ch, _ := ff.GetChan()
+
+	// the ring flush of Push panics while the lock is held
+	if err := ff.Push(13, 1); err != nil {
+		t.Fatalf("could not push: %v", err)
+	}
+
+	select {
+	case err := <-ff.Errors():
+		if !errors.Is(err, flashflood.ErrPanic) {
+			t.Fatalf("expected: %v; got %v", flashflood.ErrPanic, err)
+		}
+	default:
+		t.Fatalf("expected: panic error; got nothing")
+	}
+	if !reflect.DeepEqual(dead, []int{13}) {
+		t.Fatalf("expected: %v quarantined; got %v", []int{13}, dead)
+	}
+
+	// the instance is still alive and not locked
+	if err := ff.Push(2); err != nil {
+		t.Fatalf("could not push: %v", err)
+	}
+	select {
+	case v := <-ch:
+		if v != 1 {
+			t.Fatalf("expected: %d; got %d", 1, v)
+		}
+	default:
+		t.Fatalf("expected: element after the panic; got nothing")
+	}
+
+	if st := ff.Stats(); st.Panics != 1 || st.Failed != 1 {
+		t.Fatalf("expected 1 panic; got %+v", st)
+	}
+}
+
+func TestPanicGetNotRetained(t *testing.T) {
+	ff := flashflood.New[int](&flashflood.Opts{
+		BufferAmount: 10,
+		Timeout:      time.Second,
+	})
+	defer ff.Close()
+
+	ff.AddFunc(panicOn(13))
+	_ = ff.Push(13, 1)
+
+	if _, err := ff.Get(1); !errors.Is(err, flashflood.ErrPanic) {
+		t.Fatalf("expected: %v; got %v", flashflood.ErrPanic, err)
+	}
+	// a poison batch is never retained, it would panic on every retry
+	if ff.Count() != 1 {
+		t.Fatalf("expected 1 in buffer; got %v", ff.Count())
+	}
+}
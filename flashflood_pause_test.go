@@ -0,0 +1,153 @@
+package flashflood_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/thisisdevelopment/flashflood/v2"
+)
+
+func TestPauseResume(t *testing.T) {
+	ff := flashflood.New[int](&flashflood.Opts{
+		BufferAmount: 1,
+		GateAmount:   2,
+		Timeout:      time.Hour,
+	})
+	defer ff.Close()
+	ch, _ := ff.GetBatchInfoChan()
+
+	ff.Pause()
+	if ff.State() != flashflood.StatePaused {
+		t.Fatalf("expected: %v; got %v", flashflood.StatePaused, ff.State())
+	}
+	_ = ff.Push(1, 2, 3, 4, 5)
+
+	// the gates do not flush while paused
+	select {
+	case batch := <-ch:
+		t.Fatalf("expected: nothing while paused; got %v", batch.Items)
+	case <-time.After(60 * time.Millisecond):
+	}
+	if ff.Count() != 5 {
+		t.Fatalf("expected 5 in buffer; got %v", ff.Count())
+	}
+
+	ff.Resume()
+	if ff.State() != flashflood.StateRunning {
+		t.Fatalf("expected: %v; got %v", flashflood.StateRunning, ff.State())
+	}
+	expected := []flashflood.Batch[int]{
+		{Items: []int{1, 2}, Info: flashflood.BatchInfo{Reason: flashflood.ReasonGate}},
+		{Items: []int{3, 4}, Info: flashflood.BatchInfo{Reason: flashflood.ReasonGate}},
+	}
+	for _, e := range expected {
+		select {
+		case batch := <-ch:
+			if !reflect.DeepEqual(batch.Items, e.Items) || batch.Info.Reason != e.Info.Reason {
+				t.Fatalf("expected: %v %v; got %v %v", e.Items, e.Info.Reason, batch.Items, batch.Info.Reason)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected: %v; got nothing", e.Items)
+		}
+	}
+	if ff.Count() != 1 {
+		t.Fatalf("expected 1 in buffer; got %v", ff.Count())
+	}
+}
+
+func TestPauseTimeout(t *testing.T) {
+	ff := flashflood.New[int](&flashflood.Opts{
+		BufferAmount: 1,
+		Timeout:      10 * time.Millisecond,
+	})
+	defer ff.Close()
+	ch, _ := ff.GetBatchInfoChan()
+
+	ff.Pause()
+	_ = ff.Push(1, 2)
+	select {
+	case batch := <-ch:
+		t.Fatalf("expected: nothing while paused; got %v", batch.Items)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// the timeout passed while paused, resuming flushes right away
+	ff.Resume()
+	select {
+	case batch := <-ch:
+		if !reflect.DeepEqual(batch.Items, []int{1, 2}) || batch.Info.Reason != flashflood.ReasonTimeout {
+			t.Fatalf("expected: [1 2] timeout; got %v %v", batch.Items, batch.Info.Reason)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected: [1 2]; got nothing")
+	}
+}
+
+func TestPauseCap(t *testing.T) {
+	ff := flashflood.New[int](&flashflood.Opts{
+		BufferAmount:    1,
+		MaxBufferAmount: 3,
+	})
+	defer ff.Close()
+	_, _ = ff.GetChan()
+
+	ff.Pause()
+	if err := ff.TryPush(1, 2, 3); err != nil {
+		t.Fatalf("could not push: %v", err)
+	}
+	if err := ff.TryPush(4); !errors.Is(err, flashflood.ErrFull) {
+		t.Fatalf("expected: %v; got %v", flashflood.ErrFull, err)
+	}
+
+	// manual flushes still work while paused
+	if objs, _ := ff.Drain(false, false); len(objs) != 3 {
+		t.Fatalf("expected: 3 drained; got %v", objs)
+	}
+	if err := ff.TryPush(4); err != nil {
+		t.Fatalf("could not push: %v", err)
+	}
+}
+
+func TestStateClosed(t *testing.T) {
+	ff := flashflood.New[int](&flashflood.Opts{})
+	ff.Close()
+
+	if ff.State() != flashflood.StateClosed || ff.State().String() != "closed" {
+		t.Fatalf("expected: %v; got %v", flashflood.StateClosed, ff.State())
+	}
+	ff.Resume()
+	if ff.State() != flashflood.StateClosed {
+		t.Fatalf("expected: %v; got %v", flashflood.StateClosed, ff.State())
+	}
+}
+
+func TestKeyedPause(t *testing.T) {
+	ff := flashflood.NewKeyed[string, string](&flashflood.Opts{
+		Timeout:        10 * time.Millisecond,
+		KeyIdleTimeout: 10 * time.Millisecond,
+	}, tenantOf)
+	defer ff.Close()
+	ch, _ := ff.GetChan()
+
+	ff.Pause()
+	_ = ff.Push("a:1", "b:1")
+	select {
+	case batch := <-ch:
+		t.Fatalf("expected: nothing while paused; got %+v", batch)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if st := ff.Stats(); st.Keys != 2 {
+		t.Fatalf("expected: keys kept while paused; got %+v", st)
+	}
+
+	ff.Resume()
+	for n := 0; n < 2; n++ {
+		select {
+		case <-ch:
+		case <-time.After(time.Second):
+			t.Fatalf("expected: batch after resume; got nothing")
+		}
+	}
+}
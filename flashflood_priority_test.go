@@ -0,0 +1,130 @@
+package flashflood_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/thisisdevelopment/flashflood/v2"
+)
+
+func TestPriorityStrict(t *testing.T) {
+	ff := flashflood.New[string](&flashflood.Opts{
+		Timeout:        time.Second,
+		PriorityLevels: 3,
+	})
+	defer ff.Close()
+
+	_ = ff.Push("bulk:1", "bulk:2")
+	_ = ff.PushPriority(1, "error:1")
+	_ = ff.PushPriority(2, "control:1")
+	_ = ff.Push("bulk:3")
+	_ = ff.PushPriority(1, "error:2")
+
+	st := ff.Stats()
+	if len(st.Priority) != 3 || st.Priority[0].Buffered != 3 || st.Priority[1].Pushed != 2 || st.Priority[2].Buffered != 1 {
+		t.Fatalf("expected: 3 levels holding 3, 2 and 1 elements; got %+v", st.Priority)
+	}
+
+	objs, _ := ff.Drain(false, false)
+	expected := []string{"control:1", "error:1", "error:2", "bulk:1", "bulk:2", "bulk:3"}
+	if !reflect.DeepEqual(objs, expected) {
+		t.Fatalf("expected: %v; got %v", expected, objs)
+	}
+	if st := ff.Stats(); st.Priority[0].Released != 3 || st.Priority[0].Buffered != 0 {
+		t.Fatalf("expected: 3 released from level 0; got %+v", st.Priority[0])
+	}
+}
+
+func TestPriorityWeighted(t *testing.T) {
+	ff := flashflood.New[string](&flashflood.Opts{
+		Timeout:         time.Second,
+		PriorityLevels:  2,
+		PriorityWeights: []int{1, 2},
+	})
+	defer ff.Close()
+
+	_ = ff.Push("low:1", "low:2", "low:3")
+	_ = ff.PushPriority(1, "high:1", "high:2", "high:3", "high:4")
+
+	// the high level does not starve the low level
+	objs, _ := ff.Drain(false, false)
+	expected := []string{"high:1", "high:2", "low:1", "high:3", "high:4", "low:2", "low:3"}
+	if !reflect.DeepEqual(objs, expected) {
+		t.Fatalf("expected: %v; got %v", expected, objs)
+	}
+}
+
+func TestPriorityGate(t *testing.T) {
+	ff := flashflood.New[string](&flashflood.Opts{
+		BufferAmount:   1,
+		GateAmount:     2,
+		Timeout:        time.Second,
+		PriorityLevels: 2,
+	})
+	defer ff.Close()
+	ch, _ := ff.GetBatchChan()
+
+	_ = ff.Push("low:1", "low:2")
+	// the gate opens on the combined buffer, the urgent element leaves in the first batch
+	_ = ff.PushPriority(1, "high:1")
+
+	select {
+	case batch := <-ch:
+		if !reflect.DeepEqual(batch, []string{"high:1", "low:1"}) {
+			t.Fatalf("expected: %v; got %v", []string{"high:1", "low:1"}, batch)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected: gate batch; got nothing")
+	}
+}
+
+func TestPriorityStatsRetain(t *testing.T) {
+	ff := flashflood.New[int](&flashflood.Opts{
+		GateAmount:     2,
+		Timeout:        10 * time.Millisecond,
+		PriorityLevels: 3,
+	})
+	defer ff.Close()
+	ff.AddFuncE(failFirst(1))
+	ch, _ := ff.GetBatchChan()
+
+	// the first flush fails, the batch is retained on the highest level and retried
+	_ = ff.PushPriority(0, 1, 2)
+	select {
+	case batch := <-ch:
+		if !reflect.DeepEqual(batch, []int{1, 2}) {
+			t.Fatalf("expected: %v; got %v", []int{1, 2}, batch)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected: retried batch; got nothing")
+	}
+
+	for level, p := range ff.Stats().Priority {
+		if p.Buffered != 0 || p.Pushed != p.Released {
+			t.Fatalf("expected: level %d to release what it was pushed; got %+v", level, p)
+		}
+	}
+}
+
+func TestPriorityLevelRange(t *testing.T) {
+	ff := flashflood.New[string](&flashflood.Opts{PriorityLevels: 2})
+	defer ff.Close()
+
+	if err := ff.PushPriority(2, "x"); !errors.Is(err, flashflood.ErrPriority) {
+		t.Fatalf("expected: %v; got %v", flashflood.ErrPriority, err)
+	}
+	if err := ff.PushPriority(-1, "x"); !errors.Is(err, flashflood.ErrPriority) {
+		t.Fatalf("expected: %v; got %v", flashflood.ErrPriority, err)
+	}
+
+	plain := flashflood.New[string](&flashflood.Opts{})
+	defer plain.Close()
+	if err := plain.PushPriority(0, "x"); err != nil {
+		t.Fatalf("expected: level 0 without priorities; got %v", err)
+	}
+	if st := plain.Stats(); st.Priority != nil {
+		t.Fatalf("expected: no priority stats; got %v", st.Priority)
+	}
+}
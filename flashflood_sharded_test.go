@@ -0,0 +1,162 @@
+package flashflood_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/thisisdevelopment/flashflood/v2"
+)
+
+func TestShardedConcurrentPush(t *testing.T) {
+	ff := flashflood.NewSharded[int](8, &flashflood.Opts{
+		BufferAmount: 10,
+		Timeout:      10 * time.Millisecond,
+	})
+	ch, _ := ff.GetChan()
+
+	received := make(chan int)
+	go func() {
+		n := 0
+		for range ch {
+			n++
+		}
+		received <- n
+	}()
+
+	producers, amount := 50, 100
+	var wg sync.WaitGroup
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < amount; n++ {
+				_ = ff.Push(n)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := ff.Shutdown(context.Background()); err != nil {
+		t.Fatalf("could not shutdown: %v", err)
+	}
+
+	if n := <-received; n != producers*amount {
+		t.Fatalf("expected: %d; got %d", producers*amount, n)
+	}
+}
+
+func TestShardedKeyOrder(t *testing.T) {
+	ff := flashflood.NewSharded[[2]int](4, &flashflood.Opts{
+		BufferAmount: 1,
+		Timeout:      10 * time.Millisecond,
+	})
+	defer ff.Close()
+
+	// [key, sequence]
+	ff.SetShardKey(func(obj [2]int) uint64 { return uint64(obj[0]) })
+	ch, _ := ff.GetChan()
+
+	keys, amount := 10, 50
+	for n := 0; n < amount; n++ {
+		for k := 0; k < keys; k++ {
+			_ = ff.Push([2]int{k, n})
+		}
+	}
+
+	last := map[int]int{}
+	for n := 0; n < keys*amount; n++ {
+		select {
+		case v := <-ch:
+			if seq, ok := last[v[0]]; ok && v[1] != seq+1 {
+				t.Fatalf("expected: key %d sequence %d; got %d", v[0], seq+1, v[1])
+			}
+			last[v[0]] = v[1]
+		case <-time.After(time.Second):
+			t.Fatalf("expected: %d elements; got %d", keys*amount, n)
+		}
+	}
+}
+
+func TestShardedGatePerShard(t *testing.T) {
+	ff := flashflood.NewSharded[int](2, &flashflood.Opts{
+		BufferAmount: 1,
+		GateAmount:   3,
+		Timeout:      time.Second,
+	})
+	defer ff.Close()
+
+	ff.SetShardKey(func(obj int) uint64 { return uint64(obj % 2) })
+	ch, _ := ff.GetBatchChan()
+
+	_ = ff.Push(0, 1, 2, 3, 4)
+	select {
+	case batch := <-ch:
+		t.Fatalf("expected: no complete gate; got %v", batch)
+	default:
+	}
+
+	_ = ff.Push(6)
+	select {
+	case batch := <-ch:
+		if len(batch) != 3 || batch[0] != 0 || batch[2] != 4 {
+			t.Fatalf("expected: %v; got %v", []int{0, 2, 4}, batch)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatalf("expected: gate of the even shard; got nothing")
+	}
+
+	if ff.Count() != 3 {
+		t.Fatalf("expected 3 in buffers; got %v", ff.Count())
+	}
+}
+
+func TestShardedOutputShared(t *testing.T) {
+	ff := flashflood.NewSharded[int](3, &flashflood.Opts{})
+
+	if _, err := ff.GetBatchChan(); err != nil {
+		t.Fatalf("could not get batch channel: %v", err)
+	}
+	if _, err := ff.GetChan(); !errors.Is(err, flashflood.ErrOutputMode) {
+		t.Fatalf("expected: %v; got %v", flashflood.ErrOutputMode, err)
+	}
+
+	ff.Close()
+	if _, ok := <-ff.Errors(); ok {
+		t.Fatalf("expected: closed errors channel; got open")
+	}
+	if err := ff.Shutdown(context.Background()); !errors.Is(err, flashflood.ErrClosed) {
+		t.Fatalf("expected: %v; got %v", flashflood.ErrClosed, err)
+	}
+}
+
+func BenchmarkPushContention(b *testing.B) {
+	run := func(b *testing.B, ff flashflood.FF[int]) {
+		defer ff.Close()
+		ch, _ := ff.GetChan()
+		go func() {
+			for range ch {
+			}
+		}()
+
+		b.ReportAllocs()
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				_ = ff.Push(1)
+			}
+		})
+	}
+
+	opts := func() *flashflood.Opts {
+		return &flashflood.Opts{BufferAmount: 512, Timeout: 10 * time.Millisecond}
+	}
+	b.Run("single", func(b *testing.B) {
+		run(b, flashflood.New[int](opts()))
+	})
+	b.Run("sharded/8", func(b *testing.B) {
+		run(b, flashflood.NewSharded[int](8, opts()))
+	})
+}
@@ -0,0 +1,116 @@
+package flashflood_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/thisisdevelopment/flashflood/v2"
+)
+
+func TestShutdownDrainsAndClosesChannel(t *testing.T) {
+	ff := flashflood.New[TestObj](&flashflood.Opts{
+		BufferAmount: 10,
+		Timeout:      time.Second,
+	})
+
+	ch, err := ff.GetChan()
+	if err != nil {
+		t.Fatalf("could not get channel: %v", err)
+	}
+
+	o := getTestObjs(3)
+	_ = ff.Push(o...)
+
+	if err := ff.Shutdown(context.Background()); err != nil {
+		t.Fatalf("could not shutdown: %v", err)
+	}
+
+	drained := []TestObj{}
+	for v := range ch {
+		drained = append(drained, v)
+	}
+
+	if len(drained) != 3 {
+		t.Fatalf("expected %d drained; got %d", 3, len(drained))
+	}
+	if drained[0] != o[0] || drained[2] != o[2] {
+		t.Fatalf("expected: %#v; got %#v", o, drained)
+	}
+}
+
+func TestShutdownBatchChan(t *testing.T) {
+	ff := flashflood.New[int](&flashflood.Opts{
+		BufferAmount: 10,
+		GateAmount:   2,
+		Timeout:      time.Second,
+	})
+
+	ch, _ := ff.GetBatchChan()
+	_ = ff.Push(1, 2, 3)
+
+	if err := ff.Shutdown(context.Background()); err != nil {
+		t.Fatalf("could not shutdown: %v", err)
+	}
+
+	cnt := 0
+	for batch := range ch {
+		cnt += len(batch)
+	}
+	if cnt != 3 {
+		t.Fatalf("expected %d drained; got %d", 3, cnt)
+	}
+}
+
+func TestShutdownContextExpired(t *testing.T) {
+	ff := flashflood.New[int](&flashflood.Opts{
+		BufferAmount:  10,
+		ChannelBuffer: 1,
+		Timeout:       time.Second,
+	})
+
+	_, _ = ff.GetChan()
+	_ = ff.Push(1, 2, 3)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if err := ff.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected: %v; got %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestShutdownNotFetched(t *testing.T) {
+	ff := flashflood.New[int](&flashflood.Opts{
+		BufferAmount: 10,
+	})
+
+	_ = ff.Push(1)
+
+	if err := ff.Shutdown(context.Background()); !errors.Is(err, flashflood.ErrNotFetched) {
+		t.Fatalf("expected: %v; got %v", flashflood.ErrNotFetched, err)
+	}
+}
+
+func TestUseAfterClose(t *testing.T) {
+	ff := flashflood.New[int](&flashflood.Opts{})
+	ff.Close()
+
+	if err := ff.Push(1); !errors.Is(err, flashflood.ErrClosed) {
+		t.Fatalf("expected: %v; got %v", flashflood.ErrClosed, err)
+	}
+	if _, err := ff.Get(1); !errors.Is(err, flashflood.ErrClosed) {
+		t.Fatalf("expected: %v; got %v", flashflood.ErrClosed, err)
+	}
+	if _, err := ff.Drain(true, false); !errors.Is(err, flashflood.ErrClosed) {
+		t.Fatalf("expected: %v; got %v", flashflood.ErrClosed, err)
+	}
+	if err := ff.Shutdown(context.Background()); !errors.Is(err, flashflood.ErrClosed) {
+		t.Fatalf("expected: %v; got %v", flashflood.ErrClosed, err)
+	}
+
+	// must not panic
+	ff.Ping()
+	ff.Close()
+}
@@ -0,0 +1,95 @@
+package flashflood_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/thisisdevelopment/flashflood/v2"
+)
+
+func TestTimeoutPrecision(t *testing.T) {
+	timeout := 30 * time.Millisecond
+	ff := flashflood.New[int](&flashflood.Opts{
+		BufferAmount: 10,
+		Timeout:      timeout,
+		TickerTime:   time.Hour,
+	})
+	defer ff.Close()
+
+	ch, _ := ff.GetChan()
+	start := time.Now()
+	_ = ff.Push(1)
+
+	select {
+	case <-ch:
+		if elapsed := time.Since(start); elapsed < timeout {
+			t.Fatalf("expected: flush after %v; got %v", timeout, elapsed)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected: flush after %v; got nothing", timeout)
+	}
+}
+
+func TestTimeoutPostponedByPing(t *testing.T) {
+	timeout := 40 * time.Millisecond
+	ff := flashflood.New[int](&flashflood.Opts{
+		BufferAmount: 10,
+		Timeout:      timeout,
+	})
+	defer ff.Close()
+
+	ch, _ := ff.GetChan()
+	start := time.Now()
+	_ = ff.Push(1)
+	time.Sleep(20 * time.Millisecond)
+	ff.Ping()
+
+	select {
+	case <-ch:
+		if elapsed := time.Since(start); elapsed < 60*time.Millisecond {
+			t.Fatalf("expected: flush after %v; got %v", 60*time.Millisecond, elapsed)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected: flush; got nothing")
+	}
+}
+
+func TestMaxLatencyWithTrickle(t *testing.T) {
+	maxLatency := 50 * time.Millisecond
+	ff := flashflood.New[int](&flashflood.Opts{
+		BufferAmount: 100,
+		Timeout:      time.Second,
+		MaxLatency:   maxLatency,
+	})
+	defer ff.Close()
+
+	ch, _ := ff.GetChan()
+
+	// a steady trickle keeps postponing the idle timeout
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for n := 0; ; n++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			_ = ff.Push(n)
+			time.Sleep(5 * time.Millisecond)
+		}
+	}()
+
+	start := time.Now()
+	select {
+	case v := <-ch:
+		if v != 0 {
+			t.Fatalf("expected: %d; got %d", 0, v)
+		}
+		if elapsed := time.Since(start); elapsed < maxLatency || elapsed > 500*time.Millisecond {
+			t.Fatalf("expected: flush after %v; got %v", maxLatency, elapsed)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected: flush after %v; got nothing", maxLatency)
+	}
+}
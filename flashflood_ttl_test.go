@@ -0,0 +1,94 @@
+package flashflood_test
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/thisisdevelopment/flashflood/v2"
+)
+
+func TestElementTTLExpire(t *testing.T) {
+	ff := flashflood.New[string](&flashflood.Opts{
+		Timeout:    time.Hour,
+		ElementTTL: 30 * time.Millisecond,
+	})
+	defer ff.Close()
+
+	var mu sync.Mutex
+	var expired []string
+	ff.OnExpire(func(obj string) {
+		mu.Lock()
+		defer mu.Unlock()
+		expired = append(expired, obj)
+	})
+
+	_ = ff.Push("a", "b")
+	time.Sleep(10 * time.Millisecond)
+	_ = ff.PushWithTTL(time.Hour, "c")
+
+	// evicted by the timer, not by a method call
+	time.Sleep(100 * time.Millisecond)
+	mu.Lock()
+	got := append([]string(nil), expired...)
+	mu.Unlock()
+	if !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Fatalf("expected: %v; got %v", []string{"a", "b"}, got)
+	}
+
+	st := ff.Stats()
+	if st.Expired != 2 || st.Buffered != 1 {
+		t.Fatalf("expected: 2 expired and 1 buffered; got %+v", st)
+	}
+	objs, _ := ff.Get(10)
+	if !reflect.DeepEqual(objs, []string{"c"}) {
+		t.Fatalf("expected: %v; got %v", []string{"c"}, objs)
+	}
+}
+
+func TestElementTTLNotDelivered(t *testing.T) {
+	ff := flashflood.New[string](&flashflood.Opts{
+		Timeout: time.Hour,
+	})
+	defer ff.Close()
+
+	_ = ff.PushWithTTL(10*time.Millisecond, "ping")
+	_ = ff.Push("event")
+	time.Sleep(20 * time.Millisecond)
+
+	// expired elements are discarded before a flush, even when the timer did not run yet
+	objs, _ := ff.Drain(false, false)
+	if !reflect.DeepEqual(objs, []string{"event"}) {
+		t.Fatalf("expected: %v; got %v", []string{"event"}, objs)
+	}
+	if st := ff.Stats(); st.Expired != 1 {
+		t.Fatalf("expected: 1 expired; got %d", st.Expired)
+	}
+}
+
+func TestElementTTLBeforeTimeout(t *testing.T) {
+	ff := flashflood.New[string](&flashflood.Opts{
+		Timeout:    50 * time.Millisecond,
+		ElementTTL: 10 * time.Millisecond,
+	})
+	defer ff.Close()
+	ch, _ := ff.GetChan()
+
+	_ = ff.Push("stale")
+	_ = ff.PushWithTTL(time.Hour, "fresh")
+
+	select {
+	case v := <-ch:
+		if v != "fresh" {
+			t.Fatalf("expected: fresh; got %v", v)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected: timeout flush; got nothing")
+	}
+	select {
+	case v := <-ch:
+		t.Fatalf("expected: nothing; got %v", v)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
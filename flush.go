@@ -0,0 +1,155 @@
+package flashflood
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// interval between the checks if the consumer received the flushed elements, it doubles up to maxFlushPoll
+	minFlushPoll = 50 * time.Microsecond
+	maxFlushPoll = 5 * time.Millisecond
+)
+
+// Flush drains the buffer to the fetched channel and blocks until the consumer received every element pushed before
+// the call, or ctx is done. Unlike Drain it does not return while elements are still waiting in the channel buffer,
+// with AsyncDispatch it waits for the dispatcher as well. Elements dropped by the OverflowPolicy or a FailurePolicy
+// count as done, elements held back by PushAt are not flushed. Returns ErrNotFetched when no channel was fetched
+func (i *FlashFlood[T]) Flush(ctx context.Context) error {
+	if err := i.flushOut(ctx); err != nil {
+		return err
+	}
+	return awaitReceived(ctx, i.sent, i.pending)
+}
+
+// flushOut drains the buffer to the channel and waits until the dispatcher handled the batches queued so far
+func (i *FlashFlood[T]) flushOut(ctx context.Context) error {
+	i.mutex.Lock()
+	if i.closed.Load() {
+		i.mutex.Unlock()
+		return ErrClosed
+	}
+	if !(*i.channelFetched).IsChannelFetched() {
+		i.mutex.Unlock()
+		return ErrNotFetched
+	}
+
+	now := time.Now()
+	i.lastFlush.Store(now.UnixNano())
+	i.expire(now)
+	var err error
+	if i.buffer.len() > 0 {
+		err = i.flushGated(ctx, i.buffer.len(), false, ReasonFlush)
+		// a retained batch stays in the buffer
+		if i.buffer.len() == 0 {
+			i.clearBuffer()
+		}
+		i.signalSpace()
+	}
+	d := i.dispatcher
+	var enqueued uint64
+	if d != nil {
+		enqueued = d.enqueued
+	}
+	i.mutex.Unlock()
+
+	if err != nil || d == nil {
+		return err
+	}
+	return poll(ctx, func() (bool, error) {
+		i.mutex.Lock()
+		delivered := d.delivered
+		i.mutex.Unlock()
+		if delivered >= enqueued {
+			return true, nil
+		}
+		// Close dropped the queued batches
+		select {
+		case <-d.done:
+			return false, ErrClosed
+		default:
+		}
+		return false, nil
+	})
+}
+
+// pending returns the amount of elements or batches waiting in the channel
+func (i *FlashFlood[T]) pending() int {
+	switch i.output.Load() {
+	case outputBatches:
+		return len(i.batchChan)
+	case outputBatchInfo:
+		return len(i.batchInfoChan)
+	}
+	return len(i.floodChan)
+}
+
+// awaitReceived waits until the values sent so far left the channel. A value left once as many values were sent
+// after it as are pending, sent is read before pending so a send in between delays the result instead of advancing it
+func awaitReceived(ctx context.Context, sent *atomic.Uint64, pending func() int) error {
+	target := sent.Load()
+	return poll(ctx, func() (bool, error) {
+		n := sent.Load()
+		return n >= target+uint64(pending()), nil
+	})
+}
+
+// poll calls done until it reports true or an error, or ctx is done. A channel does not signal receives, so it is
+// checked with a growing interval
+func poll(ctx context.Context, done func() (bool, error)) error {
+	t := time.NewTimer(0)
+	stopTimer(t)
+	defer t.Stop()
+
+	for wait := minFlushPoll; ; wait = min(wait*2, maxFlushPoll) {
+		if ok, err := done(); ok || err != nil {
+			return err
+		}
+		t.Reset(wait)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Flush drains all shards to the shared channel and blocks until the consumer received every element pushed before
+// the call, or ctx is done (see FlashFlood.Flush)
+func (s *Sharded[T]) Flush(ctx context.Context) error {
+	if err := s.each(func(ff *FlashFlood[T]) error {
+		return ff.flushOut(ctx)
+	}); err != nil {
+		return err
+	}
+	return awaitReceived(ctx, s.shards[0].sent, s.shards[0].pending)
+}
+
+// Flush drains the buffers of all keys to the channel and blocks until the consumer received every element pushed
+// before the call, or ctx is done (see FlashFlood.Flush)
+func (k *Keyed[K, T]) Flush(ctx context.Context) error {
+	k.mutex.Lock()
+	closed := k.closed
+	k.mutex.Unlock()
+	if closed {
+		return ErrClosed
+	}
+	if !(*k.fetched).IsChannelFetched() {
+		return ErrNotFetched
+	}
+
+	var err error
+	for _, ff := range k.snapshot() {
+		// the buffer of a key evicted in the meantime is flushed by the eviction
+		if e := ff.flushOut(ctx); e != nil && e != ErrClosed && err == nil {
+			err = e
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return awaitReceived(ctx, k.sent, func() int {
+		return len(k.out)
+	})
+}
@@ -0,0 +1,39 @@
+package flashflood
+
+import "math"
+
+// Peek returns up to n elements in the order they leave the buffer, without removing them or postponing the timeout.
+// Elements held back by PushAt are not included
+func (i *FlashFlood[T]) Peek(n int) []T {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	if n > i.buffer.len() {
+		n = i.buffer.len()
+	}
+	if n <= 0 {
+		return nil
+	}
+
+	objs := make([]T, n)
+	for k := range objs {
+		objs[k] = i.buffer.at(k).value
+	}
+	return objs
+}
+
+// Snapshot returns a copy of all elements in the order they leave the buffer, see Peek
+func (i *FlashFlood[T]) Snapshot() []T {
+	return i.Peek(math.MaxInt)
+}
+
+// Range calls f for every element in the order they leave the buffer until f returns false, see Peek. f is called
+// while the buffer is locked, so it must not call methods of the instance
+func (i *FlashFlood[T]) Range(f func(k int, obj T) bool) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	for k := 0; k < i.buffer.len(); k++ {
+		if !f(k, i.buffer.at(k).value) {
+			return
+		}
+	}
+}
@@ -0,0 +1,427 @@
+package flashflood
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// KeyedBatch a batch of elements sharing the same key, delivered on the channel of Keyed.GetChan
+type KeyedBatch[K comparable, T any] struct {
+	Key   K
+	Items []T
+	Info  BatchInfo
+}
+
+// KeyedStats counters of a Keyed
+type KeyedStats struct {
+	Stats
+	// amount of keys buffering
+	Keys int
+	// amount of keys removed after KeyIdleTimeout
+	Evicted uint64
+}
+
+// Keyed groups elements by key, every key has its own buffer applying GateAmount, Timeout and the FuncStack, so
+// batches never mix keys. The buffers of all keys share one Scheduler and one output channel
+type Keyed[K comparable, T any] struct {
+	key     func(obj T) K
+	opts    *Opts
+	mutex   *sync.Mutex
+	buffers map[K]*FlashFlood[T]
+	closed  bool
+	paused  bool
+
+	out     chan KeyedBatch[K, T]
+	sent    *atomic.Uint64
+	fetched *ChannelFetchedStatus
+	errs    chan error
+
+	funcs []stackFunc[T]
+	sizer func(obj T) int
+
+	// the Scheduler is owned by Keyed unless set in the Opts
+	scheduler    *Scheduler
+	ownScheduler bool
+	evictTimer   *time.Timer
+	evicting     *sync.WaitGroup
+	evicted      uint64
+}
+
+// NewKeyed returns a new Keyed grouping the elements by the key returned by key. opts configures the buffer of every
+// key, Opts.MaxKeys limits the amount of keys and Opts.KeyIdleTimeout removes the buffers of idle keys
+func NewKeyed[K comparable, T any](opts *Opts, key func(obj T) K) *Keyed[K, T] {
+	opts = handleOpts(opts)
+	nfs := NewChannelFetchedStatus()
+
+	// the buffers of the keys share the Scheduler, so they do not run a goroutine each
+	bufferOpts := *opts
+	k := &Keyed[K, T]{
+		key:       key,
+		opts:      &bufferOpts,
+		mutex:     &sync.Mutex{},
+		evicting:  &sync.WaitGroup{},
+		buffers:   map[K]*FlashFlood[T]{},
+		out:       make(chan KeyedBatch[K, T], opts.ChannelBuffer),
+		sent:      &atomic.Uint64{},
+		fetched:   &nfs,
+		errs:      make(chan error, defaultErrorBuffer),
+		scheduler: opts.Scheduler,
+	}
+	if k.scheduler == nil {
+		k.scheduler = NewScheduler()
+		k.ownScheduler = true
+		bufferOpts.Scheduler = k.scheduler
+	}
+	return k
+}
+
+// buffer returns the buffer of key, creating it when needed. Make sure we have a mutex Lock
+func (k *Keyed[K, T]) buffer(key K) (*FlashFlood[T], error) {
+	if k.closed {
+		return nil, ErrClosed
+	}
+	if ff, ok := k.buffers[key]; ok {
+		return ff, nil
+	}
+	// only keys holding elements count, the buffers of flushed keys make room
+	if k.opts.MaxKeys > 0 && len(k.buffers) >= k.opts.MaxKeys {
+		k.dropEmpty()
+	}
+	if k.opts.MaxKeys > 0 && len(k.buffers) >= k.opts.MaxKeys {
+		return nil, ErrMaxKeys
+	}
+
+	ff := newSinkInstance[T](k.opts, k.errs, k.fetched)
+	ff.sizer = k.sizer
+	for _, f := range k.funcs {
+		ff.addFunc(f)
+	}
+	ff.sink = func(ctx context.Context, objs []T, info BatchInfo) error {
+		return send(ctx, k.out, KeyedBatch[K, T]{Key: key, Items: objs, Info: info}, ff.opts, func(b KeyedBatch[K, T]) {
+			ff.dropBatch(b.Items)
+		}, k.sent)
+	}
+	if k.paused {
+		ff.Pause()
+	}
+
+	k.buffers[key] = ff
+	k.armEviction()
+	return ff, nil
+}
+
+// dropEmpty removes the buffers of the keys without elements, a push that got hold of one retries with a new buffer.
+// Make sure we have a mutex Lock
+func (k *Keyed[K, T]) dropEmpty() {
+	for key, ff := range k.buffers {
+		if ff.closeIfEmpty() {
+			delete(k.buffers, key)
+		}
+	}
+}
+
+// Push add objects to the buffers of their keys
+func (k *Keyed[K, T]) Push(objs ...T) error {
+	return k.PushContext(context.Background(), objs...)
+}
+
+// PushContext add objects to the buffers of their keys, blocks until there is room below MaxBufferAmount or ctx is
+// done. Consecutive objects with the same key are pushed at once
+func (k *Keyed[K, T]) PushContext(ctx context.Context, objs ...T) error {
+	return k.pushRuns(objs, func(ff *FlashFlood[T], run []T) error {
+		return ff.PushContext(ctx, run...)
+	})
+}
+
+// TryPush add objects to the buffers of their keys, returns ErrFull instead of blocking. The objects before the run
+// that did not fit are pushed
+func (k *Keyed[K, T]) TryPush(objs ...T) error {
+	return k.pushRuns(objs, func(ff *FlashFlood[T], run []T) error {
+		return ff.TryPush(run...)
+	})
+}
+
+// pushRuns calls push for every run of consecutive objects with the same key
+func (k *Keyed[K, T]) pushRuns(objs []T, push func(ff *FlashFlood[T], run []T) error) error {
+	for start := 0; start < len(objs); {
+		key := k.key(objs[start])
+		end := start + 1
+		for end < len(objs) && k.key(objs[end]) == key {
+			end++
+		}
+
+		for {
+			k.mutex.Lock()
+			ff, err := k.buffer(key)
+			k.mutex.Unlock()
+			if err != nil {
+				return err
+			}
+			err = push(ff, objs[start:end])
+			// the buffer was evicted in the meantime, the key gets a new one
+			if err == ErrClosed && !k.isClosed() {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			break
+		}
+		start = end
+	}
+	return nil
+}
+
+func (k *Keyed[K, T]) isClosed() bool {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	return k.closed
+}
+
+// GetChan get the channel delivering the batches of every key
+func (k *Keyed[K, T]) GetChan() (<-chan KeyedBatch[K, T], error) {
+	if k.isClosed() {
+		return nil, ErrClosed
+	}
+	(*k.fetched).ChannelFetched()
+	return k.out, nil
+}
+
+// Errors returns the channel receiving the asynchronous errors of every key, see FlashFlood.Errors
+func (k *Keyed[K, T]) Errors() <-chan error {
+	return k.errs
+}
+
+// AddFunc add a "callback" function to the callstack of every key
+func (k *Keyed[K, T]) AddFunc(f FuncStack[T]) {
+	k.addFunc(func(objs []T, _ BatchInfo, ff *FlashFlood[T]) ([]T, error) {
+		return f(objs, ff), nil
+	})
+}
+
+// AddFuncInfo add a "callback" function receiving the batch metadata to the callstack of every key
+func (k *Keyed[K, T]) AddFuncInfo(f FuncStackInfo[T]) {
+	k.addFunc(func(objs []T, info BatchInfo, ff *FlashFlood[T]) ([]T, error) {
+		return f(objs, info, ff), nil
+	})
+}
+
+// AddFuncE add a "callback" function that can fail to the callstack of every key
+func (k *Keyed[K, T]) AddFuncE(f FuncStackE[T]) {
+	k.addFunc(func(objs []T, _ BatchInfo, ff *FlashFlood[T]) ([]T, error) {
+		return f(objs, ff)
+	})
+}
+
+func (k *Keyed[K, T]) addFunc(f stackFunc[T]) {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	k.funcs = append(k.funcs, f)
+	for _, ff := range k.buffers {
+		ff.addFunc(f)
+	}
+}
+
+// SetSizer sets the function returning the size of an element for every key, see FlashFlood.SetSizer
+func (k *Keyed[K, T]) SetSizer(f func(obj T) int) {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	k.sizer = f
+	for _, ff := range k.buffers {
+		ff.SetSizer(f)
+	}
+}
+
+// Drain flushes the buffers of all keys to the channel
+func (k *Keyed[K, T]) Drain() error {
+	var err error
+	for _, ff := range k.snapshot() {
+		if _, e := ff.Drain(true, false); e != nil && e != ErrClosed && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// Count returns amount of elements in the buffers of all keys
+func (k *Keyed[K, T]) Count() uint64 {
+	var cnt uint64
+	for _, ff := range k.snapshot() {
+		cnt += ff.Count()
+	}
+	return cnt
+}
+
+// Stats returns the counters of all keys added up
+func (k *Keyed[K, T]) Stats() KeyedStats {
+	k.mutex.Lock()
+	st := KeyedStats{Keys: len(k.buffers), Evicted: k.evicted}
+	k.mutex.Unlock()
+
+	for _, ff := range k.snapshot() {
+		st.Stats = st.Stats.add(ff.Stats())
+	}
+	return st
+}
+
+// Pause stops flushing for every key, new keys start paused and no key is evicted, see FlashFlood.Pause
+func (k *Keyed[K, T]) Pause() {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	k.paused = true
+	for _, ff := range k.buffers {
+		ff.Pause()
+	}
+}
+
+// Resume starts flushing again for every key, see FlashFlood.Resume
+func (k *Keyed[K, T]) Resume() {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	k.paused = false
+	for _, ff := range k.buffers {
+		ff.Resume()
+	}
+}
+
+// State returns if Keyed is running, paused or closed
+func (k *Keyed[K, T]) State() State {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	switch {
+	case k.closed:
+		return StateClosed
+	case k.paused:
+		return StatePaused
+	}
+	return StateRunning
+}
+
+// snapshot returns the buffers of all keys
+func (k *Keyed[K, T]) snapshot() []*FlashFlood[T] {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	buffers := make([]*FlashFlood[T], 0, len(k.buffers))
+	for _, ff := range k.buffers {
+		buffers = append(buffers, ff)
+	}
+	return buffers
+}
+
+// armEviction starts the eviction timer when keys are buffering, make sure we have a mutex Lock
+func (k *Keyed[K, T]) armEviction() {
+	if k.opts.KeyIdleTimeout <= 0 || k.evictTimer != nil {
+		return
+	}
+	k.evictTimer = time.AfterFunc(k.opts.KeyIdleTimeout, k.evict)
+}
+
+// evict flushes and removes the buffers of the keys idle for KeyIdleTimeout and rearms the timer for the next key to
+// become idle
+func (k *Keyed[K, T]) evict() {
+	now := time.Now()
+
+	k.mutex.Lock()
+	if k.closed {
+		k.mutex.Unlock()
+		return
+	}
+	// evicting flushes, so keys are kept while paused
+	if k.paused {
+		k.evictTimer.Reset(k.opts.KeyIdleTimeout)
+		k.mutex.Unlock()
+		return
+	}
+	var idle []*FlashFlood[T]
+	next := k.opts.KeyIdleTimeout
+	for key, ff := range k.buffers {
+		elapsed := now.Sub(time.Unix(0, ff.lastAction.Load()))
+		if elapsed >= k.opts.KeyIdleTimeout {
+			idle = append(idle, ff)
+			delete(k.buffers, key)
+			continue
+		}
+		if wait := k.opts.KeyIdleTimeout - elapsed; wait < next {
+			next = wait
+		}
+	}
+	k.evicted += uint64(len(idle))
+	if len(k.buffers) > 0 {
+		k.evictTimer.Reset(next)
+	} else {
+		k.evictTimer = nil
+	}
+	// Close and Shutdown wait for the evicted buffers to be flushed before closing the channels
+	k.evicting.Add(1)
+	defer k.evicting.Done()
+	k.mutex.Unlock()
+
+	for _, ff := range idle {
+		if err := ff.shutdown(context.Background(), ReasonEvict); err != nil && err != ErrClosed && err != ErrNotFetched {
+			ff.mutex.Lock()
+			ff.reportError(err)
+			ff.unlock()
+		}
+	}
+}
+
+// Close closes the buffers of all keys, elements left in the buffers are dropped (see Shutdown)
+func (k *Keyed[K, T]) Close() {
+	buffers, ok := k.stop()
+	if !ok {
+		return
+	}
+	for _, ff := range buffers {
+		ff.close()
+	}
+	k.release()
+}
+
+// Shutdown flushes the buffers of all keys to the channel and closes it
+func (k *Keyed[K, T]) Shutdown(ctx context.Context) error {
+	buffers, ok := k.stop()
+	if !ok {
+		return ErrClosed
+	}
+
+	var err error
+	for _, ff := range buffers {
+		if e := ff.shutdown(ctx, ReasonShutdown); e != nil && e != ErrClosed && err == nil {
+			err = e
+		}
+	}
+	k.release()
+	close(k.out)
+	return err
+}
+
+// stop marks Keyed closed and returns the buffers of all keys, false when it was closed already
+func (k *Keyed[K, T]) stop() ([]*FlashFlood[T], bool) {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	if k.closed {
+		return nil, false
+	}
+	k.closed = true
+	if k.evictTimer != nil {
+		k.evictTimer.Stop()
+	}
+
+	buffers := make([]*FlashFlood[T], 0, len(k.buffers))
+	for _, ff := range k.buffers {
+		buffers = append(buffers, ff)
+	}
+	k.buffers = map[K]*FlashFlood[T]{}
+	return buffers, true
+}
+
+// release frees the resources shared by the buffers once they are all closed
+func (k *Keyed[K, T]) release() {
+	k.evicting.Wait()
+	if k.ownScheduler {
+		k.scheduler.Close()
+	}
+	close(k.errs)
+}
@@ -0,0 +1,71 @@
+package flashflood
+
+// RemoveFunc removes the elements pred returns true for from the buffer, including the elements held back by PushAt,
+// and returns the amount removed. pred is called while the buffer is locked, so it must not call methods of the
+// instance
+func (i *FlashFlood[T]) RemoveFunc(pred func(obj T) bool) int {
+	return i.RetainFunc(func(obj T) bool {
+		return !pred(obj)
+	})
+}
+
+// RetainFunc keeps only the elements keep returns true for in the buffer, including the elements held back by PushAt,
+// and returns the amount removed. The kept elements keep their order, see RemoveFunc
+func (i *FlashFlood[T]) RetainFunc(keep func(obj T) bool) int {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	before := i.buffer.len() + i.delayed.len()
+	i.buffer.filter(func(e *entry[T]) bool {
+		if keep(e.value) {
+			return true
+		}
+		i.bytes -= e.size
+		return false
+	})
+	i.delayed.filter(func(obj *T) bool {
+		return keep(*obj)
+	})
+
+	removed := before - i.buffer.len() - i.delayed.len()
+	if removed > 0 {
+		i.signalSpace()
+	}
+	return removed
+}
+
+// Replace replaces the elements pred returns true for with obj, including the elements held back by PushAt, and
+// returns the amount replaced. Replaced elements keep their place in the buffer, see RemoveFunc
+func (i *FlashFlood[T]) Replace(pred func(obj T) bool, obj T) int {
+	replaced := 0
+	i.UpdateFunc(func(old T) T {
+		if !pred(old) {
+			return old
+		}
+		replaced++
+		return obj
+	})
+	return replaced
+}
+
+// UpdateFunc replaces every element in the buffer, including the elements held back by PushAt, by the result of
+// update. Updated elements keep their place in the buffer (their fairness key, priority and deadline are not
+// reevaluated). update is called while the buffer is locked, so it must not call methods of the instance
+func (i *FlashFlood[T]) UpdateFunc(update func(obj T) T) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	i.buffer.filter(func(e *entry[T]) bool {
+		e.value = update(e.value)
+		if i.sizer != nil {
+			i.bytes -= e.size
+			e.size = i.sizer(e.value)
+			i.bytes += e.size
+		}
+		return true
+	})
+	i.delayed.filter(func(obj *T) bool {
+		*obj = update(*obj)
+		return true
+	})
+}
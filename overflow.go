@@ -0,0 +1,98 @@
+package flashflood
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// OverflowPolicy determines what happens when elements are flushed while the channel is full
+type OverflowPolicy int
+
+const (
+	// OverflowBlock blocks the flush until the consumer makes room on the channel (default)
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropNewest drops the elements being flushed when the channel is full
+	OverflowDropNewest
+	// OverflowDropOldest drops the oldest elements waiting on the channel to make room for the elements being flushed
+	OverflowDropOldest
+	// OverflowBlockWithTimeout blocks up to Opts.OverflowTimeout and drops the elements being flushed after that
+	OverflowBlockWithTimeout
+)
+
+// OnDrop sets a callback called for every element dropped by the overflow policy. f may be called while the buffer is
+// locked, so it must not call methods of the instance
+func (i *FlashFlood[T]) OnDrop(f func(obj T)) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	i.onDrop = f
+}
+
+func (i *FlashFlood[T]) dropElement(obj T) {
+	i.stats.dropped.Add(1)
+	if i.onDrop != nil {
+		i.onDrop(obj)
+	}
+}
+
+func (i *FlashFlood[T]) dropBatch(objs []T) {
+	for _, obj := range objs {
+		i.dropElement(obj)
+	}
+}
+
+func (i *FlashFlood[T]) dropBatchInfo(b Batch[T]) {
+	i.dropBatch(b.Items)
+}
+
+// send delivers v on ch according to the overflow policy, drop is called with whatever did not make it on the channel.
+// sent counts the values that made it on the channel (see Flush)
+func send[E any](ctx context.Context, ch chan E, v E, opts *Opts, drop func(E), sent *atomic.Uint64) error {
+	// fast path, there is room on the channel
+	select {
+	case ch <- v:
+		sent.Add(1)
+		return nil
+	default:
+	}
+
+	switch opts.OverflowPolicy {
+	case OverflowDropNewest:
+		drop(v)
+		return nil
+	case OverflowDropOldest:
+		for {
+			select {
+			case old := <-ch:
+				drop(old)
+			default:
+			}
+			select {
+			case ch <- v:
+				sent.Add(1)
+				return nil
+			default:
+			}
+		}
+	case OverflowBlockWithTimeout:
+		t := time.NewTimer(opts.OverflowTimeout)
+		defer t.Stop()
+		select {
+		case ch <- v:
+			sent.Add(1)
+		case <-t.C:
+			drop(v)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		return nil
+	default:
+		select {
+		case ch <- v:
+			sent.Add(1)
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package flashflood
+
+import "time"
+
+// State the state of an instance, see State
+type State int
+
+const (
+	// StateRunning elements are flushed by the gates and timeouts
+	StateRunning State = iota
+	// StatePaused elements accumulate in the buffer, see Pause
+	StatePaused
+	// StateClosed the instance was closed or shut down
+	StateClosed
+)
+
+func (s State) String() string {
+	switch s {
+	case StateRunning:
+		return "running"
+	case StatePaused:
+		return "paused"
+	case StateClosed:
+		return "closed"
+	}
+	return "unknown"
+}
+
+// Pause stops flushing, no gate, timeout, flush timeout or max latency releases elements until Resume. Push keeps
+// accepting elements up to MaxBufferAmount. Get, GetOnChan, Drain and Shutdown still flush, elements still expire
+// and delayed elements still move into the buffer
+func (i *FlashFlood[T]) Pause() {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	if i.closed.Load() || i.paused {
+		return
+	}
+	i.paused = true
+	// the timeouts are no longer due
+	i.wakeTimer()
+}
+
+// Resume starts flushing again, the backlog is released per GateAmount as by a Push and the timeouts that passed
+// while paused fire right away
+func (i *FlashFlood[T]) Resume() {
+	i.mutex.Lock()
+	defer i.unlock()
+	if i.closed.Load() || !i.paused {
+		return
+	}
+	i.paused = false
+	i.releaseOnPush(time.Now())
+	i.wakeTimer()
+}
+
+// State returns if the instance is running, paused or closed
+func (i *FlashFlood[T]) State() State {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	switch {
+	case i.closed.Load():
+		return StateClosed
+	case i.paused:
+		return StatePaused
+	}
+	return StateRunning
+}
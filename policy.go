@@ -0,0 +1,233 @@
+package flashflood
+
+import (
+	"time"
+)
+
+// BufferStats snapshot of the buffer handed to a FlushPolicy
+type BufferStats struct {
+	// amount of elements in the buffer
+	Count int
+	// accumulated size of the elements in the buffer, 0 without a sizer (see SetSizer)
+	Bytes int
+	// time since the oldest element in the buffer was pushed
+	OldestAge time.Duration
+	// time since the last action (see Ping)
+	Idle time.Duration
+	// time since the last flush
+	SinceFlush time.Duration
+	// amount of elements due within DeadlineSlack of their deadline (with DeadlineOrdered)
+	Due int
+	// time until the next element is due (with DeadlineOrdered)
+	NextDue time.Duration
+}
+
+// FlushPolicy decides when elements are released from the buffer. It is consulted on every Push and when the
+// timer fires, the released elements are flushed in batches of GateAmount
+type FlushPolicy interface {
+	// Release returns the amount of elements to release from the front of the buffer and why
+	Release(s BufferStats) (int, FlushReason)
+	// Wait returns the time after which Release should be consulted again, false when no timer is needed
+	Wait(s BufferStats) (time.Duration, bool)
+}
+
+// CountPolicy keeps Amount elements in the buffer and releases the overflow per Gate elements (the ring buffer)
+type CountPolicy struct {
+	Amount int64
+	Gate   int64
+}
+
+// Release returns the overflow above Amount, rounded down to Gate
+func (p CountPolicy) Release(s BufferStats) (int, FlushReason) {
+	toDrain := int64(s.Count) - p.Amount
+	if p.Gate <= 1 {
+		if toDrain > 0 {
+			return int(toDrain), ReasonOverflow
+		}
+		return 0, ReasonOverflow
+	}
+	return int(toDrain / p.Gate * p.Gate), ReasonGate
+}
+
+// Wait CountPolicy only acts on Push
+func (p CountPolicy) Wait(BufferStats) (time.Duration, bool) {
+	return 0, false
+}
+
+// BytesPolicy releases the whole buffer once it holds Bytes or more (requires a sizer, see SetSizer)
+type BytesPolicy struct {
+	Bytes int
+}
+
+// Release returns the whole buffer once it holds Bytes or more
+func (p BytesPolicy) Release(s BufferStats) (int, FlushReason) {
+	if s.Bytes >= p.Bytes {
+		return s.Count, ReasonGate
+	}
+	return 0, ReasonGate
+}
+
+// Wait BytesPolicy only acts on Push
+func (p BytesPolicy) Wait(BufferStats) (time.Duration, bool) {
+	return 0, false
+}
+
+// AgePolicy releases the whole buffer once its oldest element was pushed MaxAge ago
+type AgePolicy struct {
+	MaxAge time.Duration
+}
+
+// Release returns the whole buffer once its oldest element reached MaxAge
+func (p AgePolicy) Release(s BufferStats) (int, FlushReason) {
+	return releaseAfter(s, s.OldestAge, p.MaxAge), ReasonMaxLatency
+}
+
+// Wait returns the time until the oldest element reaches MaxAge
+func (p AgePolicy) Wait(s BufferStats) (time.Duration, bool) {
+	return waitFor(s, s.OldestAge, p.MaxAge)
+}
+
+// IdlePolicy releases the whole buffer after Timeout without any action (see Ping)
+type IdlePolicy struct {
+	Timeout time.Duration
+}
+
+// Release returns the whole buffer once it was idle for Timeout
+func (p IdlePolicy) Release(s BufferStats) (int, FlushReason) {
+	return releaseAfter(s, s.Idle, p.Timeout), ReasonTimeout
+}
+
+// Wait returns the time until the buffer is idle for Timeout
+func (p IdlePolicy) Wait(s BufferStats) (time.Duration, bool) {
+	return waitFor(s, s.Idle, p.Timeout)
+}
+
+// IntervalPolicy releases the whole buffer when the last flush was Interval ago
+type IntervalPolicy struct {
+	Interval time.Duration
+}
+
+// Release returns the whole buffer once the last flush was Interval ago
+func (p IntervalPolicy) Release(s BufferStats) (int, FlushReason) {
+	return releaseAfter(s, s.SinceFlush, p.Interval), ReasonFlushTimeout
+}
+
+// Wait returns the time until the last flush was Interval ago
+func (p IntervalPolicy) Wait(s BufferStats) (time.Duration, bool) {
+	return waitFor(s, s.SinceFlush, p.Interval)
+}
+
+// AnyPolicy releases as soon as one of its policies does, the largest amount (and its reason) wins
+func AnyPolicy(policies ...FlushPolicy) FlushPolicy {
+	return anyPolicy(policies)
+}
+
+type anyPolicy []FlushPolicy
+
+func (p anyPolicy) Release(s BufferStats) (int, FlushReason) {
+	release, reason := 0, ReasonPolicy
+	for _, policy := range p {
+		if r, why := policy.Release(s); r > release {
+			release, reason = r, why
+		}
+	}
+	return release, reason
+}
+
+func (p anyPolicy) Wait(s BufferStats) (time.Duration, bool) {
+	var wait time.Duration
+	found := false
+	for _, policy := range p {
+		if w, ok := policy.Wait(s); ok && (!found || w < wait) {
+			wait, found = w, true
+		}
+	}
+	return wait, found
+}
+
+// AllPolicy releases only when all of its policies do, the smallest amount (and its reason) wins
+func AllPolicy(policies ...FlushPolicy) FlushPolicy {
+	return allPolicy(policies)
+}
+
+type allPolicy []FlushPolicy
+
+func (p allPolicy) Release(s BufferStats) (int, FlushReason) {
+	release, reason := 0, ReasonPolicy
+	for n, policy := range p {
+		r, why := policy.Release(s)
+		if r == 0 {
+			return 0, ReasonPolicy
+		}
+		if n == 0 || r < release {
+			release, reason = r, why
+		}
+	}
+	return release, reason
+}
+
+// Wait returns the largest wait of the policies with a timer. Once those are due while a policy without a timer (count
+// or bytes) still holds the release back there is nothing to wait for, the next Push consults the policy again
+func (p allPolicy) Wait(s BufferStats) (time.Duration, bool) {
+	var wait time.Duration
+	found, untimed := false, false
+	for _, policy := range p {
+		w, ok := policy.Wait(s)
+		if !ok {
+			untimed = true
+			continue
+		}
+		if !found || w > wait {
+			wait = w
+		}
+		found = true
+	}
+	if found && wait <= 0 && untimed {
+		if r, _ := p.Release(s); r == 0 {
+			return 0, false
+		}
+	}
+	return wait, found
+}
+
+// DefaultFlushPolicy returns the policy used when Opts.FlushPolicy is not set: the ring of BufferAmount released per
+// GateAmount, GateBytes, Timeout (deadlines when DeadlineOrdered), FlushTimeout (when FlushEnabled) and MaxLatency. Use it to extend the default behavior
+//
+//	opts.FlushPolicy = flashflood.AnyPolicy(flashflood.DefaultFlushPolicy(opts), flashflood.BytesPolicy{Bytes: 1 << 20})
+func DefaultFlushPolicy(opts *Opts) FlushPolicy {
+	opts = handleOpts(opts)
+
+	policies := anyPolicy{
+		CountPolicy{Amount: opts.BufferAmount, Gate: opts.GateAmount},
+	}
+	// with deadlines every element times out on its own
+	if opts.DeadlineOrdered {
+		policies = append(policies, DeadlinePolicy{})
+	} else {
+		policies = append(policies, IdlePolicy{Timeout: opts.Timeout})
+	}
+	if opts.GateBytes > 0 {
+		policies = append(policies, BytesPolicy{Bytes: opts.GateBytes})
+	}
+	if opts.FlushEnabled {
+		policies = append(policies, IntervalPolicy{Interval: opts.FlushTimeout})
+	}
+	if opts.MaxLatency > 0 {
+		policies = append(policies, AgePolicy{MaxAge: opts.MaxLatency})
+	}
+	return policies
+}
+
+func releaseAfter(s BufferStats, elapsed time.Duration, limit time.Duration) int {
+	if s.Count > 0 && elapsed >= limit {
+		return s.Count
+	}
+	return 0
+}
+
+func waitFor(s BufferStats, elapsed time.Duration, limit time.Duration) (time.Duration, bool) {
+	if s.Count == 0 {
+		return 0, false
+	}
+	return limit - elapsed, true
+}
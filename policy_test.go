@@ -0,0 +1,217 @@
+package flashflood_test
+
+import (
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/thisisdevelopment/flashflood/v2"
+)
+
+func TestPolicyRelease(t *testing.T) {
+	s := flashflood.BufferStats{
+		Count:      10,
+		Bytes:      100,
+		OldestAge:  time.Second,
+		Idle:       100 * time.Millisecond,
+		SinceFlush: 500 * time.Millisecond,
+		Due:        4,
+	}
+
+	tests := []struct {
+		name    string
+		policy  flashflood.FlushPolicy
+		release int
+		reason  flashflood.FlushReason
+	}{
+		{"count ring", flashflood.CountPolicy{Amount: 3}, 7, flashflood.ReasonOverflow},
+		{"count gate", flashflood.CountPolicy{Amount: 3, Gate: 3}, 6, flashflood.ReasonGate},
+		{"count below", flashflood.CountPolicy{Amount: 20}, 0, flashflood.ReasonOverflow},
+		{"bytes", flashflood.BytesPolicy{Bytes: 100}, 10, flashflood.ReasonGate},
+		{"bytes below", flashflood.BytesPolicy{Bytes: 101}, 0, flashflood.ReasonGate},
+		{"age", flashflood.AgePolicy{MaxAge: time.Second}, 10, flashflood.ReasonMaxLatency},
+		{"age below", flashflood.AgePolicy{MaxAge: 2 * time.Second}, 0, flashflood.ReasonMaxLatency},
+		{"idle", flashflood.IdlePolicy{Timeout: 50 * time.Millisecond}, 10, flashflood.ReasonTimeout},
+		{"idle below", flashflood.IdlePolicy{Timeout: time.Second}, 0, flashflood.ReasonTimeout},
+		{"interval", flashflood.IntervalPolicy{Interval: 500 * time.Millisecond}, 10, flashflood.ReasonFlushTimeout},
+		{"deadline", flashflood.DeadlinePolicy{}, 4, flashflood.ReasonDeadline},
+		{"any", flashflood.AnyPolicy(flashflood.CountPolicy{Amount: 8}, flashflood.IdlePolicy{Timeout: time.Second}), 2, flashflood.ReasonOverflow},
+		{"any largest", flashflood.AnyPolicy(flashflood.CountPolicy{Amount: 8}, flashflood.IdlePolicy{Timeout: 50 * time.Millisecond}), 10, flashflood.ReasonTimeout},
+		{"all", flashflood.AllPolicy(flashflood.CountPolicy{Amount: 8}, flashflood.BytesPolicy{Bytes: 50}), 2, flashflood.ReasonOverflow},
+		{"all below", flashflood.AllPolicy(flashflood.CountPolicy{Amount: 8}, flashflood.BytesPolicy{Bytes: 500}), 0, flashflood.ReasonPolicy},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, reason := tt.policy.Release(s)
+			if r != tt.release {
+				t.Fatalf("expected: %d; got %d", tt.release, r)
+			}
+			if reason != tt.reason {
+				t.Fatalf("expected: %v; got %v", tt.reason, reason)
+			}
+		})
+	}
+}
+
+func TestPolicyWait(t *testing.T) {
+	s := flashflood.BufferStats{
+		Count:     1,
+		OldestAge: 100 * time.Millisecond,
+		Idle:      50 * time.Millisecond,
+	}
+
+	p := flashflood.AnyPolicy(
+		flashflood.CountPolicy{Amount: 3},
+		flashflood.AgePolicy{MaxAge: time.Second},
+		flashflood.IdlePolicy{Timeout: 200 * time.Millisecond},
+	)
+	if w, ok := p.Wait(s); !ok || w != 150*time.Millisecond {
+		t.Fatalf("expected: %v; got %v %v", 150*time.Millisecond, w, ok)
+	}
+
+	p = flashflood.AllPolicy(
+		flashflood.AgePolicy{MaxAge: time.Second},
+		flashflood.IdlePolicy{Timeout: 200 * time.Millisecond},
+	)
+	if w, ok := p.Wait(s); !ok || w != 900*time.Millisecond {
+		t.Fatalf("expected: %v; got %v %v", 900*time.Millisecond, w, ok)
+	}
+
+	if _, ok := (flashflood.CountPolicy{Amount: 3}).Wait(s); ok {
+		t.Fatalf("expected: no timer for CountPolicy; got timer")
+	}
+	if _, ok := p.Wait(flashflood.BufferStats{}); ok {
+		t.Fatalf("expected: no timer for an empty buffer; got timer")
+	}
+}
+
+func TestFlushPolicyBytes(t *testing.T) {
+	opts := &flashflood.Opts{
+		BufferAmount: 100,
+		Timeout:      time.Second,
+	}
+	opts.FlushPolicy = flashflood.AnyPolicy(flashflood.DefaultFlushPolicy(opts), flashflood.BytesPolicy{Bytes: 10})
+
+	ff := flashflood.New[string](opts)
+	defer ff.Close()
+	ff.SetSizer(func(s string) int { return len(s) })
+
+	ch, _ := ff.GetBatchChan()
+
+	_ = ff.Push("aaaa", "bbbb")
+	select {
+	case batch := <-ch:
+		t.Fatalf("expected: nothing below 10 bytes; got %v", batch)
+	default:
+	}
+
+	_ = ff.Push("cc")
+	select {
+	case batch := <-ch:
+		if !reflect.DeepEqual(batch, []string{"aaaa", "bbbb", "cc"}) {
+			t.Fatalf("expected: %v; got %v", []string{"aaaa", "bbbb", "cc"}, batch)
+		}
+	default:
+		t.Fatalf("expected: batch at 10 bytes; got nothing")
+	}
+}
+
+func TestFlushPolicyCustom(t *testing.T) {
+	// only an age policy, the ring and idle timeout of the default policy no longer apply
+	ff := flashflood.New[int](&flashflood.Opts{
+		BufferAmount: 1,
+		Timeout:      10 * time.Millisecond,
+		FlushPolicy:  flashflood.AgePolicy{MaxAge: 50 * time.Millisecond},
+	})
+	defer ff.Close()
+
+	ch, _ := ff.GetBatchChan()
+	start := time.Now()
+	_ = ff.Push(1, 2, 3)
+
+	select {
+	case batch := <-ch:
+		if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+			t.Fatalf("expected: flush after %v; got %v", 50*time.Millisecond, elapsed)
+		}
+		if len(batch) != 3 {
+			t.Fatalf("expected: 3 elements; got %v", batch)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected: age flush; got nothing")
+	}
+}
+
+// countingPolicy counts the calls of Release
+type countingPolicy struct {
+	flashflood.FlushPolicy
+	calls *atomic.Int64
+}
+
+func (p countingPolicy) Release(s flashflood.BufferStats) (int, flashflood.FlushReason) {
+	p.calls.Add(1)
+	return p.FlushPolicy.Release(s)
+}
+
+// stuckPolicy is always due but never releases
+type stuckPolicy struct{}
+
+func (stuckPolicy) Release(flashflood.BufferStats) (int, flashflood.FlushReason) {
+	return 0, flashflood.ReasonPolicy
+}
+
+func (stuckPolicy) Wait(flashflood.BufferStats) (time.Duration, bool) {
+	return -time.Second, true
+}
+
+func TestFlushPolicyNoSpin(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy flashflood.FlushPolicy
+	}{
+		{"all idle bytes", flashflood.AllPolicy(flashflood.IdlePolicy{Timeout: 10 * time.Millisecond}, flashflood.BytesPolicy{Bytes: 100})},
+		{"custom", stuckPolicy{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int64
+			ff := flashflood.New[int](&flashflood.Opts{
+				FlushPolicy: countingPolicy{FlushPolicy: tt.policy, calls: &calls},
+			})
+			defer ff.Close()
+			_, _ = ff.GetBatchChan()
+
+			_ = ff.Push(1)
+			time.Sleep(100 * time.Millisecond)
+			if n := calls.Load(); n > 10 {
+				t.Fatalf("expected: a few Release calls; got %v", n)
+			}
+		})
+	}
+}
+
+func TestFlushPolicyAllAfterPush(t *testing.T) {
+	// the idle timeout passes while the bytes are missing, the push completing them arms the timer again
+	ff := flashflood.New[int](&flashflood.Opts{
+		FlushPolicy: flashflood.AllPolicy(flashflood.IdlePolicy{Timeout: 10 * time.Millisecond}, flashflood.BytesPolicy{Bytes: 100}),
+	})
+	defer ff.Close()
+	ff.SetSizer(func(int) int { return 60 })
+	ch, _ := ff.GetBatchChan()
+
+	_ = ff.Push(1)
+	time.Sleep(50 * time.Millisecond)
+	_ = ff.Push(2)
+
+	select {
+	case batch := <-ch:
+		if !reflect.DeepEqual(batch, []int{1, 2}) {
+			t.Fatalf("expected: %v; got %v", []int{1, 2}, batch)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected: flush once idle with 120 bytes; got nothing")
+	}
+}
@@ -0,0 +1,237 @@
+package flashflood
+
+import (
+	"context"
+	"math"
+)
+
+// PriorityStats counters of a priority level
+type PriorityStats struct {
+	// amount of elements of the level in the buffer
+	Buffered uint64
+	// amount of elements pushed on the level, unshifted and retained elements count as pushed on the highest level
+	Pushed uint64
+	// amount of elements that left the buffer from the level
+	Released uint64
+}
+
+// priorityQueue a queue per priority level. Without weights the highest level holding elements releases first
+// (strict), with weights the levels take turns from the highest to the lowest, every turn releasing as many elements
+// as the weight of the level
+type priorityQueue[T any] struct {
+	levels  []queue[T]
+	weights []int
+	count   int
+
+	pushed   []uint64
+	released []uint64
+
+	// the level having the turn and the elements it may still release with weights
+	cur     int
+	deficit int
+
+	// the order computed by at so far with weights, reset on every modification
+	order  []*entry[T]
+	peeked []int
+	steps  int
+	credit int
+}
+
+func newPriorityQueue[T any](levels []queue[T], weights []int) *priorityQueue[T] {
+	return &priorityQueue[T]{
+		levels:   levels,
+		weights:  weights,
+		pushed:   make([]uint64, len(levels)),
+		released: make([]uint64, len(levels)),
+		cur:      len(levels) - 1,
+		peeked:   make([]int, len(levels)),
+	}
+}
+
+func (q *priorityQueue[T]) len() int {
+	return q.count
+}
+
+// pushBack adds e to the lowest level, see pushAt
+func (q *priorityQueue[T]) pushBack(e entry[T]) {
+	q.pushAt(0, e)
+}
+
+func (q *priorityQueue[T]) pushAt(level int, e entry[T]) {
+	q.levels[level].pushBack(e)
+	q.pushed[level]++
+	q.count++
+	q.modified()
+}
+
+// pushFront adds e to the front of the highest level, unshifted and retained elements leave before the other elements
+// of that level. They count as pushed on it, so every level releases what it was pushed
+func (q *priorityQueue[T]) pushFront(e entry[T]) {
+	top := len(q.levels) - 1
+	q.levels[top].pushFront(e)
+	q.pushed[top]++
+	q.count++
+	q.modified()
+}
+
+func (q *priorityQueue[T]) popFront() entry[T] {
+	level := q.next()
+	e := q.levels[level].popFront()
+	q.released[level]++
+	q.count--
+	q.modified()
+	return e
+}
+
+// next returns the level releasing the next element and takes it from the turn with weights
+func (q *priorityQueue[T]) next() int {
+	if len(q.weights) == 0 {
+		level := len(q.levels) - 1
+		for q.levels[level].len() == 0 {
+			level--
+		}
+		return level
+	}
+
+	for q.levels[q.cur].len() == 0 {
+		q.pass()
+	}
+	if q.deficit == 0 {
+		q.deficit = q.weight(q.cur)
+	}
+	level := q.cur
+	q.deficit--
+	if q.deficit == 0 || q.levels[level].len() == 1 {
+		q.pass()
+	}
+	return level
+}
+
+// pass hands the turn to the next lower level, after the lowest level the highest gets the turn again
+func (q *priorityQueue[T]) pass() {
+	q.cur = q.below(q.cur, 1)
+	q.deficit = 0
+}
+
+// below returns the level steps turns after level
+func (q *priorityQueue[T]) below(level, steps int) int {
+	n := len(q.levels)
+	return ((level-steps)%n + n) % n
+}
+
+// weight returns the amount of elements level releases per turn
+func (q *priorityQueue[T]) weight(level int) int {
+	if level < len(q.weights) && q.weights[level] > 1 {
+		return q.weights[level]
+	}
+	return 1
+}
+
+// at returns the k-th element to leave the queue, with weights by playing the turns ahead like fairQueue.at
+func (q *priorityQueue[T]) at(k int) *entry[T] {
+	if len(q.weights) == 0 {
+		for level := len(q.levels) - 1; ; level-- {
+			if n := q.levels[level].len(); k >= n {
+				k -= n
+				continue
+			}
+			return q.levels[level].at(k)
+		}
+	}
+
+	for len(q.order) <= k {
+		level := q.below(q.cur, q.steps)
+		if q.peeked[level] == q.levels[level].len() {
+			q.steps++
+			q.credit = 0
+			continue
+		}
+		if q.credit == 0 {
+			q.credit = q.weight(level)
+			// the level having the turn continues it
+			if q.steps == 0 && q.deficit > 0 {
+				q.credit = q.deficit
+			}
+		}
+
+		q.order = append(q.order, q.levels[level].at(q.peeked[level]))
+		q.peeked[level]++
+		q.credit--
+		if q.credit == 0 {
+			q.steps++
+		}
+	}
+	return q.order[k]
+}
+
+func (q *priorityQueue[T]) oldest() int64 {
+	oldest := int64(math.MaxInt64)
+	for _, level := range q.levels {
+		if level.len() > 0 && level.oldest() < oldest {
+			oldest = level.oldest()
+		}
+	}
+	return oldest
+}
+
+func (q *priorityQueue[T]) reset() {
+	for _, level := range q.levels {
+		level.reset()
+	}
+	q.count = 0
+	q.cur = len(q.levels) - 1
+	q.deficit = 0
+	q.modified()
+}
+
+func (q *priorityQueue[T]) filter(keep func(e *entry[T]) bool) {
+	q.count = 0
+	for _, level := range q.levels {
+		level.filter(keep)
+		q.count += level.len()
+	}
+	q.modified()
+}
+
+// modified drops the order computed by at
+func (q *priorityQueue[T]) modified() {
+	if len(q.weights) == 0 {
+		return
+	}
+	q.order = q.order[:0]
+	clear(q.peeked)
+	q.steps = 0
+	q.credit = 0
+}
+
+// stats returns the counters per level
+func (q *priorityQueue[T]) stats() []PriorityStats {
+	st := make([]PriorityStats, len(q.levels))
+	for level := range st {
+		st[level] = PriorityStats{
+			Buffered: uint64(q.levels[level].len()),
+			Pushed:   q.pushed[level],
+			Released: q.released[level],
+		}
+	}
+	return st
+}
+
+// PushPriority add objects to buffer at a priority level between 0 (the level of Push) and Opts.PriorityLevels-1,
+// higher levels leave the buffer first. Blocks while the buffer is at MaxBufferAmount
+func (i *FlashFlood[T]) PushPriority(level int, objs ...T) error {
+	return i.PushPriorityContext(context.Background(), level, objs...)
+}
+
+// PushPriorityContext add objects to buffer at a priority level, blocks until there is room below MaxBufferAmount or
+// ctx is done
+func (i *FlashFlood[T]) PushPriorityContext(ctx context.Context, level int, objs ...T) error {
+	levels := 1
+	if q, ok := i.buffer.(*priorityQueue[T]); ok {
+		levels = len(q.levels)
+	}
+	if level < 0 || level >= levels {
+		return ErrPriority
+	}
+	return i.pushContext(ctx, placement{level: level}, objs)
+}
@@ -0,0 +1,37 @@
+package flashflood
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// TestPriorityPeekOrder at must predict the order popFront releases the elements in
+func TestPriorityPeekOrder(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	opts := handleOpts(&Opts{PriorityLevels: 3, PriorityWeights: []int{1, 2, 3}, Fairness: FairnessRoundRobin})
+	q := newQueue[int](opts, 0).(*priorityQueue[int])
+	for _, level := range q.levels {
+		level.(*fairQueue[int]).key = func(obj int) string { return string(rune('a' + obj%3)) }
+	}
+
+	for round := 0; round < 200; round++ {
+		for n := rnd.Intn(10); n > 0; n-- {
+			if rnd.Intn(5) == 0 {
+				q.pushFront(entry[int]{value: rnd.Intn(100)})
+			} else {
+				q.pushAt(rnd.Intn(3), entry[int]{value: rnd.Intn(100)})
+			}
+		}
+
+		peeked := make([]int, q.len())
+		for k := range peeked {
+			peeked[k] = q.at(k).value
+		}
+		pops := rnd.Intn(q.len() + 1)
+		for k := 0; k < pops; k++ {
+			if v := q.popFront().value; v != peeked[k] {
+				t.Fatalf("expected: %d at %d; got %d", peeked[k], k, v)
+			}
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package flashflood
+
+import "time"
+
+// queue holds the buffered elements and decides the order they leave the buffer in. Make sure we have a mutex Lock
+// for every call
+type queue[T any] interface {
+	// len returns the amount of elements in the queue
+	len() int
+	// at returns the k-th element to leave the queue, valid until the queue is modified
+	at(k int) *entry[T]
+	// pushBack adds a pushed element
+	pushBack(e entry[T])
+	// pushFront adds an unshifted or retained element, it leaves before the elements pushed earlier
+	pushFront(e entry[T])
+	// popFront removes and returns the next element to leave the queue
+	popFront() entry[T]
+	// oldest returns the unix nano time the oldest element was pushed, the queue must not be empty
+	oldest() int64
+	// reset removes all elements
+	reset()
+	// filter removes the elements keep returns false for
+	filter(keep func(e *entry[T]) bool)
+}
+
+// fifo the default queue, elements leave in the order they were pushed
+type fifo[T any] struct {
+	ring[entry[T]]
+}
+
+// oldest the front of the ring always holds the oldest element
+func (q *fifo[T]) oldest() int64 {
+	return q.at(0).pushed
+}
+
+// placement where PushPriority and PushDeadline put the elements in the queue
+type placement struct {
+	// priority level, see priorityQueue
+	level int
+	// unix nano deadline, 0 is the default deadline, see deadlineQueue
+	deadline int64
+	// time to live, 0 is Opts.ElementTTL
+	ttl time.Duration
+	// unix nano time the elements are held back until, see PushAt
+	at int64
+}
+
+// newQueue returns the queue for the ordering configured in opts, preallocated for capacity elements
+func newQueue[T any](opts *Opts, capacity int) queue[T] {
+	if opts.DeadlineOrdered {
+		return newDeadlineQueue[T](opts, capacity)
+	}
+	if opts.PriorityLevels > 1 {
+		levels := make([]queue[T], opts.PriorityLevels)
+		for level := range levels {
+			levels[level] = newLevelQueue[T](opts, capacity)
+		}
+		return newPriorityQueue[T](levels, opts.PriorityWeights)
+	}
+	return newLevelQueue[T](opts, capacity)
+}
+
+// newLevelQueue returns the queue of a single priority level
+func newLevelQueue[T any](opts *Opts, capacity int) queue[T] {
+	if opts.Fairness != FairnessNone {
+		return newFairQueue[T](opts.Fairness)
+	}
+	return &fifo[T]{newRing[entry[T]](capacity)}
+}
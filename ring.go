@@ -0,0 +1,97 @@
+package flashflood
+
+// ring circular array backing the buffer, push and pop on both ends are O(1). It grows by doubling when full, so a
+// buffer sized for its steady state never allocates
+type ring[E any] struct {
+	items []E
+	// index of the front element
+	head  int
+	count int
+}
+
+func newRing[E any](capacity int) ring[E] {
+	size := 1
+	for size < capacity {
+		size <<= 1
+	}
+	return ring[E]{items: make([]E, size)}
+}
+
+// len returns the amount of elements in the ring
+func (r *ring[E]) len() int {
+	return r.count
+}
+
+// at returns the k-th element from the front
+func (r *ring[E]) at(k int) *E {
+	return &r.items[(r.head+k)&(len(r.items)-1)]
+}
+
+// pushBack adds e to the back
+func (r *ring[E]) pushBack(e E) {
+	r.grow()
+	r.items[(r.head+r.count)&(len(r.items)-1)] = e
+	r.count++
+}
+
+// pushFront adds e to the front
+func (r *ring[E]) pushFront(e E) {
+	r.grow()
+	r.head = (r.head - 1) & (len(r.items) - 1)
+	r.items[r.head] = e
+	r.count++
+}
+
+// popFront removes and returns the front element
+func (r *ring[E]) popFront() E {
+	var zero E
+	e := r.items[r.head]
+	// release the reference for the garbage collector
+	r.items[r.head] = zero
+	r.head = (r.head + 1) & (len(r.items) - 1)
+	r.count--
+	return e
+}
+
+// reset removes all elements, the capacity is kept
+func (r *ring[E]) reset() {
+	var zero E
+	for k := 0; k < r.count; k++ {
+		*r.at(k) = zero
+	}
+	r.head = 0
+	r.count = 0
+}
+
+// filter removes the elements keep returns false for, the order of the kept elements is preserved
+func (r *ring[E]) filter(keep func(e *E) bool) {
+	var zero E
+	n := 0
+	for k := 0; k < r.count; k++ {
+		if e := r.at(k); keep(e) {
+			*r.at(n) = *e
+			n++
+		}
+	}
+	for k := n; k < r.count; k++ {
+		*r.at(k) = zero
+	}
+	r.count = n
+}
+
+func (r *ring[E]) grow() {
+	if r.count < len(r.items) {
+		return
+	}
+
+	size := len(r.items) * 2
+	if size == 0 {
+		size = 1
+	}
+	items := make([]E, size)
+	for k := 0; k < r.count; k++ {
+		items[k] = *r.at(k)
+	}
+	r.items = items
+	r.head = 0
+}
@@ -0,0 +1,102 @@
+package flashflood
+
+import (
+	"testing"
+	"time"
+)
+
+func ringValues(r *ring[int]) []int {
+	values := make([]int, r.len())
+	for k := range values {
+		values[k] = *r.at(k)
+	}
+	return values
+}
+
+func TestRingWrapAround(t *testing.T) {
+	r := newRing[int](4)
+	for n := 0; n < 10; n++ {
+		r.pushBack(n)
+		if v := r.popFront(); v != n {
+			t.Fatalf("expected: %d; got %d", n, v)
+		}
+	}
+	if len(r.items) != 4 {
+		t.Fatalf("expected: capacity 4; got %d", len(r.items))
+	}
+
+	r.pushBack(1)
+	r.pushBack(2)
+	r.pushFront(0)
+	r.pushFront(-1)
+	if v := ringValues(&r); len(v) != 4 || v[0] != -1 || v[3] != 2 {
+		t.Fatalf("expected: [-1 0 1 2]; got %v", v)
+	}
+	if len(r.items) != 4 {
+		t.Fatalf("expected: capacity 4; got %d", len(r.items))
+	}
+}
+
+func TestRingGrow(t *testing.T) {
+	r := newRing[int](3)
+	r.pushBack(1)
+	r.pushBack(2)
+	_ = r.popFront()
+	for n := 3; n <= 6; n++ {
+		r.pushBack(n)
+	}
+	r.pushFront(1)
+
+	if v := ringValues(&r); len(v) != 6 || v[0] != 1 || v[5] != 6 {
+		t.Fatalf("expected: [1 2 3 4 5 6]; got %v", v)
+	}
+	if len(r.items) != 8 {
+		t.Fatalf("expected: capacity 8; got %d", len(r.items))
+	}
+
+	r.reset()
+	if r.len() != 0 || len(r.items) != 8 {
+		t.Fatalf("expected: empty ring keeping capacity 8; got %d of %d", r.len(), len(r.items))
+	}
+	r.pushBack(7)
+	if v := r.popFront(); v != 7 {
+		t.Fatalf("expected: %d; got %d", 7, v)
+	}
+}
+
+func TestPushAllocs(t *testing.T) {
+	ff := New[int](&Opts{BufferAmount: 8, Timeout: time.Hour})
+	defer ff.Close()
+	_, _ = ff.GetChan()
+	// fill the ring, from now on every push releases one element
+	_ = ff.Push(1, 2, 3, 4, 5, 6, 7, 8)
+
+	allocs := testing.AllocsPerRun(1000, func() {
+		_ = ff.Push(1)
+		<-ff.floodChan
+	})
+	if allocs != 0 {
+		t.Fatalf("expected: 0 allocs per push; got %v", allocs)
+	}
+}
+
+func TestRingFilter(t *testing.T) {
+	r := newRing[int](4)
+	r.pushBack(0)
+	r.pushBack(0)
+	_ = r.popFront()
+	_ = r.popFront()
+	// wraps around the end of items
+	for n := 1; n <= 4; n++ {
+		r.pushBack(n)
+	}
+
+	r.filter(func(v *int) bool { return *v%2 == 0 })
+	if v := ringValues(&r); len(v) != 2 || v[0] != 2 || v[1] != 4 {
+		t.Fatalf("expected: [2 4]; got %v", v)
+	}
+	r.pushBack(5)
+	if v := ringValues(&r); len(v) != 3 || v[2] != 5 {
+		t.Fatalf("expected: [2 4 5]; got %v", v)
+	}
+}
@@ -0,0 +1,237 @@
+package flashflood
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+)
+
+// Scheduler drives the timeouts of many instances from a single goroutine and timer, see Opts.Scheduler.
+// A drain blocking on a full channel delays the timeouts of every instance on the Scheduler, use a non blocking
+// OverflowPolicy when instances are consumed at different speeds
+type Scheduler struct {
+	mutex   *sync.Mutex
+	targets map[schedulable]*scheduled
+	queue   *scheduleQueue
+	dirty   map[schedulable]struct{}
+	wake    chan struct{}
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     *sync.WaitGroup
+
+	fired         uint64
+	totalLateness time.Duration
+	maxLateness   time.Duration
+}
+
+// SchedulerStats counters of a Scheduler
+type SchedulerStats struct {
+	// amount of instances registered
+	Registered int
+	// amount of instances with a pending timer
+	Scheduled int
+	// amount of timers fired
+	Fired uint64
+	// average time between the deadline and the moment the timer fired
+	AvgLateness time.Duration
+	// maximum time between the deadline and the moment the timer fired
+	MaxLateness time.Duration
+}
+
+// schedulable is implemented by the instances a Scheduler drives
+type schedulable interface {
+	// nextWake returns the time until the next timeout is due, false when there is nothing to wait for
+	nextWake(now time.Time) (time.Duration, bool)
+	// onWake handles the timeouts due at now
+	onWake(now time.Time)
+}
+
+type scheduled struct {
+	target   schedulable
+	deadline time.Time
+	// position in the queue, -1 when not queued
+	index int
+}
+
+// NewScheduler returns a new Scheduler and starts its goroutine
+func NewScheduler() *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Scheduler{
+		mutex:   &sync.Mutex{},
+		targets: map[schedulable]*scheduled{},
+		queue:   &scheduleQueue{},
+		dirty:   map[schedulable]struct{}{},
+		wake:    make(chan struct{}, 1),
+		ctx:     ctx,
+		cancel:  cancel,
+		wg:      &sync.WaitGroup{},
+	}
+
+	s.wg.Add(1)
+	go s.run()
+	return s
+}
+
+// Close stops the Scheduler, registered instances no longer time out
+func (s *Scheduler) Close() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+// Stats returns the counters of the Scheduler
+func (s *Scheduler) Stats() SchedulerStats {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	st := SchedulerStats{
+		Registered:  len(s.targets),
+		Scheduled:   s.queue.Len(),
+		Fired:       s.fired,
+		MaxLateness: s.maxLateness,
+	}
+	if s.fired > 0 {
+		st.AvgLateness = s.totalLateness / time.Duration(s.fired)
+	}
+	return st
+}
+
+func (s *Scheduler) register(t schedulable) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.targets[t] = &scheduled{target: t, index: -1}
+}
+
+func (s *Scheduler) unregister(t schedulable) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if e, ok := s.targets[t]; ok && e.index >= 0 {
+		heap.Remove(s.queue, e.index)
+	}
+	delete(s.targets, t)
+	delete(s.dirty, t)
+}
+
+// reschedule asks the Scheduler to recalculate the deadline of t, safe to call while holding the lock of t
+func (s *Scheduler) reschedule(t schedulable) {
+	s.mutex.Lock()
+	s.dirty[t] = struct{}{}
+	s.mutex.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Scheduler) run() {
+	defer s.wg.Done()
+
+	timer := time.NewTimer(0)
+	stopTimer(timer)
+
+	for {
+		var fire <-chan time.Time
+		s.mutex.Lock()
+		if s.queue.Len() > 0 {
+			timer.Reset(time.Until((*s.queue)[0].deadline))
+			fire = timer.C
+		}
+		s.mutex.Unlock()
+
+		select {
+		case <-s.ctx.Done():
+			stopTimer(timer)
+			return
+		case <-s.wake:
+			stopTimer(timer)
+		case now := <-fire:
+			s.fire(now)
+		}
+
+		s.update()
+	}
+}
+
+// fire calls onWake of every target due at now
+func (s *Scheduler) fire(now time.Time) {
+	var due []schedulable
+
+	s.mutex.Lock()
+	for s.queue.Len() > 0 && !(*s.queue)[0].deadline.After(now) {
+		e := heap.Pop(s.queue).(*scheduled)
+		lateness := now.Sub(e.deadline)
+		s.fired++
+		s.totalLateness += lateness
+		if lateness > s.maxLateness {
+			s.maxLateness = lateness
+		}
+		due = append(due, e.target)
+		// the target decides on its next deadline after handling this one
+		s.dirty[e.target] = struct{}{}
+	}
+	s.mutex.Unlock()
+
+	for _, t := range due {
+		t.onWake(now)
+	}
+}
+
+// update recalculates the deadlines of the targets marked dirty
+func (s *Scheduler) update() {
+	s.mutex.Lock()
+	dirty := s.dirty
+	s.dirty = map[schedulable]struct{}{}
+	s.mutex.Unlock()
+
+	now := time.Now()
+	for t := range dirty {
+		// nextWake takes the lock of the target, so never call it while holding the Scheduler lock
+		wait, ok := t.nextWake(now)
+
+		s.mutex.Lock()
+		e, registered := s.targets[t]
+		switch {
+		case !registered:
+		case !ok && e.index >= 0:
+			heap.Remove(s.queue, e.index)
+		case ok && e.index >= 0:
+			e.deadline = now.Add(wait)
+			heap.Fix(s.queue, e.index)
+		case ok:
+			e.deadline = now.Add(wait)
+			heap.Push(s.queue, e)
+		}
+		s.mutex.Unlock()
+	}
+}
+
+// scheduleQueue min heap of deadlines
+type scheduleQueue []*scheduled
+
+func (q scheduleQueue) Len() int           { return len(q) }
+func (q scheduleQueue) Less(a, b int) bool { return q[a].deadline.Before(q[b].deadline) }
+
+func (q scheduleQueue) Swap(a, b int) {
+	q[a], q[b] = q[b], q[a]
+	q[a].index = a
+	q[b].index = b
+}
+
+func (q *scheduleQueue) Push(x any) {
+	e := x.(*scheduled)
+	e.index = len(*q)
+	*q = append(*q, e)
+}
+
+func (q *scheduleQueue) Pop() any {
+	old := *q
+	n := len(old)
+	e := old[n-1]
+	old[n-1] = nil
+	e.index = -1
+	*q = old[:n-1]
+	return e
+}
@@ -0,0 +1,88 @@
+package flashflood_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/thisisdevelopment/flashflood/v2"
+)
+
+func TestSchedulerManyInstances(t *testing.T) {
+	s := flashflood.NewScheduler()
+	defer s.Close()
+
+	amount := 100
+	instances := make([]*flashflood.FlashFlood[int], amount)
+	chans := make([]<-chan int, amount)
+	for n := range instances {
+		instances[n] = flashflood.New[int](&flashflood.Opts{
+			BufferAmount: 10,
+			Timeout:      time.Duration(10+n%5) * time.Millisecond,
+			Scheduler:    s,
+		})
+		chans[n], _ = instances[n].GetChan()
+	}
+
+	if st := s.Stats(); st.Registered != amount || st.Scheduled != 0 {
+		t.Fatalf("expected: %d registered and nothing scheduled; got %+v", amount, st)
+	}
+
+	for n, ff := range instances {
+		_ = ff.Push(n)
+	}
+
+	for n, ch := range chans {
+		select {
+		case v := <-ch:
+			if v != n {
+				t.Fatalf("expected: %d; got %d", n, v)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected: timeout flush of instance %d; got nothing", n)
+		}
+	}
+
+	st := s.Stats()
+	if st.Fired < uint64(amount) {
+		t.Fatalf("expected at least %d fired; got %d", amount, st.Fired)
+	}
+	if st.MaxLateness < st.AvgLateness {
+		t.Fatalf("expected: max lateness >= avg lateness; got %+v", st)
+	}
+
+	for _, ff := range instances {
+		ff.Close()
+	}
+
+	if st := s.Stats(); st.Registered != 0 || st.Scheduled != 0 {
+		t.Fatalf("expected: nothing registered; got %+v", st)
+	}
+}
+
+func TestSchedulerFlushTimeout(t *testing.T) {
+	s := flashflood.NewScheduler()
+	defer s.Close()
+
+	ff := flashflood.New[int](&flashflood.Opts{
+		BufferAmount: 10,
+		Timeout:      time.Second,
+		FlushEnabled: true,
+		FlushTimeout: 30 * time.Millisecond,
+		Scheduler:    s,
+	})
+	defer ff.Close()
+
+	ch, _ := ff.GetChan()
+
+	// keep the instance active, so only the flush timeout can drain it
+	for n := 0; n < 5; n++ {
+		_ = ff.Push(n)
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	select {
+	case <-ch:
+	case <-time.After(time.Second):
+		t.Fatalf("expected: flush timeout drain; got nothing")
+	}
+}
@@ -0,0 +1,450 @@
+package flashflood
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// Sharded spreads the elements over multiple instances, each with its own lock, buffer and timer, so producers do not
+// contend on a single lock. All shards deliver on the same channel, gates, timeouts and the FuncStack apply per shard
+type Sharded[T any] struct {
+	shards []*FlashFlood[T]
+	next   *atomic.Uint64
+	key    func(obj T) uint64
+}
+
+var _ FF[int] = (*Sharded[int])(nil)
+
+// NewSharded returns a new Sharded with the given amount of shards, every shard is an instance configured by opts.
+// Elements are spread round-robin per call, use SetShardKey to keep elements with the same key on the same shard
+func NewSharded[T any](shards int, opts *Opts) *Sharded[T] {
+	if shards < 1 {
+		shards = 1
+	}
+
+	s := &Sharded[T]{
+		shards: make([]*FlashFlood[T], shards),
+		next:   &atomic.Uint64{},
+	}
+	for n := range s.shards {
+		s.shards[n] = New[T](opts)
+		if n > 0 {
+			s.shards[n].shareOutput(s.shards[0])
+		}
+	}
+	return s
+}
+
+// shareOutput makes i deliver on the channels of with, the output mode and the Errors channel are shared as well
+func (i *FlashFlood[T]) shareOutput(with *FlashFlood[T]) {
+	// the batch channels are created up front, a shard may flush right after another one fetched them
+	with.initBatchChan()
+	with.initBatchInfoChan()
+
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	i.floodChan = with.floodChan
+	i.batchChan = with.batchChan
+	i.batchOnce = with.batchOnce
+	i.batchInfoChan = with.batchInfoChan
+	i.batchInfoOnce = with.batchInfoOnce
+	i.output = with.output
+	i.sent = with.sent
+	i.channelFetched = with.channelFetched
+	i.errs = with.errs
+}
+
+// SetShardKey sets the function choosing the shard of an element, elements with the same key keep their order. Set
+// it before pushing
+func (s *Sharded[T]) SetShardKey(f func(obj T) uint64) {
+	s.key = f
+}
+
+// pick returns the next shard round-robin
+func (s *Sharded[T]) pick() *FlashFlood[T] {
+	return s.shards[s.next.Add(1)%uint64(len(s.shards))]
+}
+
+func (s *Sharded[T]) shardOf(obj T) *FlashFlood[T] {
+	return s.shards[s.key(obj)%uint64(len(s.shards))]
+}
+
+// each calls f for every shard, starting at a rotating shard so no shard is favored, and returns the first error
+func (s *Sharded[T]) each(f func(ff *FlashFlood[T]) error) error {
+	var err error
+	start := int(s.next.Add(1) % uint64(len(s.shards)))
+	for n := range s.shards {
+		if e := f(s.shards[(start+n)%len(s.shards)]); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// Push add objects to a shard, blocks while the shard is at MaxBufferAmount
+func (s *Sharded[T]) Push(objs ...T) error {
+	return s.PushContext(context.Background(), objs...)
+}
+
+// PushContext add objects to a shard, blocks until there is room below MaxBufferAmount or ctx is done
+func (s *Sharded[T]) PushContext(ctx context.Context, objs ...T) error {
+	if s.key == nil {
+		return s.pick().PushContext(ctx, objs...)
+	}
+	for _, obj := range objs {
+		if err := s.shardOf(obj).PushContext(ctx, obj); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TryPush add objects to a shard, returns ErrFull instead of blocking. With a shard key the objects before the one
+// that did not fit are pushed
+func (s *Sharded[T]) TryPush(objs ...T) error {
+	if s.key == nil {
+		return s.pick().TryPush(objs...)
+	}
+	for _, obj := range objs {
+		if err := s.shardOf(obj).TryPush(obj); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PushPriority add objects to a shard at a priority level, see FlashFlood.PushPriority
+func (s *Sharded[T]) PushPriority(level int, objs ...T) error {
+	if s.key == nil {
+		return s.pick().PushPriority(level, objs...)
+	}
+	for _, obj := range objs {
+		if err := s.shardOf(obj).PushPriority(level, obj); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PushWithTTL add objects to a shard with a TTL, see FlashFlood.PushWithTTL
+func (s *Sharded[T]) PushWithTTL(ttl time.Duration, objs ...T) error {
+	if s.key == nil {
+		return s.pick().PushWithTTL(ttl, objs...)
+	}
+	for _, obj := range objs {
+		if err := s.shardOf(obj).PushWithTTL(ttl, obj); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PushAt add objects to a shard at t, see FlashFlood.PushAt
+func (s *Sharded[T]) PushAt(t time.Time, objs ...T) error {
+	if s.key == nil {
+		return s.pick().PushAt(t, objs...)
+	}
+	for _, obj := range objs {
+		if err := s.shardOf(obj).PushAt(t, obj); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PushAfter add objects to a shard after d, see FlashFlood.PushAt
+func (s *Sharded[T]) PushAfter(d time.Duration, objs ...T) error {
+	return s.PushAt(time.Now().Add(d), objs...)
+}
+
+// Unshift add objects to the front of a shard
+func (s *Sharded[T]) Unshift(objs ...T) error {
+	if s.key == nil {
+		return s.pick().Unshift(objs...)
+	}
+	for n := len(objs) - 1; n >= 0; n-- {
+		if err := s.shardOf(objs[n]).Unshift(objs[n]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Get amount of elements from the shards
+func (s *Sharded[T]) Get(amount int) ([]T, error) {
+	var objs []T
+	err := s.each(func(ff *FlashFlood[T]) error {
+		if len(objs) >= amount {
+			return nil
+		}
+		got, err := ff.Get(amount - len(objs))
+		objs = append(objs, got...)
+		return err
+	})
+	return objs, err
+}
+
+// GetOnChan amount of elements from the shards, flush to channel
+func (s *Sharded[T]) GetOnChan(amount int) error {
+	return s.each(func(ff *FlashFlood[T]) error {
+		if amount <= 0 {
+			return nil
+		}
+		n := int(ff.Count())
+		if n > amount {
+			n = amount
+		}
+		amount -= n
+		return ff.GetOnChan(n)
+	})
+}
+
+// Drain drains all shards into channel or as slice (onChannel bool)
+func (s *Sharded[T]) Drain(onChannel bool, respectGate bool) ([]T, error) {
+	var objs []T
+	err := s.each(func(ff *FlashFlood[T]) error {
+		drained, err := ff.Drain(onChannel, respectGate)
+		objs = append(objs, drained...)
+		return err
+	})
+	return objs, err
+}
+
+// GetChan get the shared overflow channel
+func (s *Sharded[T]) GetChan() (<-chan T, error) {
+	return s.shards[0].GetChan()
+}
+
+// GetBatchChan get the shared overflow channel delivering the batches of every shard
+func (s *Sharded[T]) GetBatchChan() (<-chan []T, error) {
+	return s.shards[0].GetBatchChan()
+}
+
+// GetBatchInfoChan get the shared overflow channel delivering the batches of every shard with their metadata, the
+// sequence numbers are per shard
+func (s *Sharded[T]) GetBatchInfoChan() (<-chan Batch[T], error) {
+	return s.shards[0].GetBatchInfoChan()
+}
+
+// Errors returns the shared channel receiving the asynchronous errors of every shard
+func (s *Sharded[T]) Errors() <-chan error {
+	return s.shards[0].Errors()
+}
+
+// AddFunc add a "callback" function to the callstack of every shard
+func (s *Sharded[T]) AddFunc(f FuncStack[T]) {
+	for _, ff := range s.shards {
+		ff.AddFunc(f)
+	}
+}
+
+// AddFuncInfo add a "callback" function receiving the batch metadata to the callstack of every shard
+func (s *Sharded[T]) AddFuncInfo(f FuncStackInfo[T]) {
+	for _, ff := range s.shards {
+		ff.AddFuncInfo(f)
+	}
+}
+
+// AddFuncE add a "callback" function that can fail to the callstack of every shard
+func (s *Sharded[T]) AddFuncE(f FuncStackE[T]) {
+	for _, ff := range s.shards {
+		ff.AddFuncE(f)
+	}
+}
+
+// SetSizer sets the function returning the size of an element on every shard
+func (s *Sharded[T]) SetSizer(f func(obj T) int) {
+	for _, ff := range s.shards {
+		ff.SetSizer(f)
+	}
+}
+
+// SetFairnessKey sets the function returning the key elements are interleaved by on every shard, see
+// FlashFlood.SetFairnessKey
+func (s *Sharded[T]) SetFairnessKey(f func(obj T) string) {
+	for _, ff := range s.shards {
+		ff.SetFairnessKey(f)
+	}
+}
+
+// SetFairnessWeight sets the function returning the weight of a key on every shard
+func (s *Sharded[T]) SetFairnessWeight(f func(key string) int) {
+	for _, ff := range s.shards {
+		ff.SetFairnessWeight(f)
+	}
+}
+
+// OnDrop sets the callback for dropped elements on every shard
+func (s *Sharded[T]) OnDrop(f func(obj T)) {
+	for _, ff := range s.shards {
+		ff.OnDrop(f)
+	}
+}
+
+// OnExpire sets the callback for expired elements on every shard
+func (s *Sharded[T]) OnExpire(f func(obj T)) {
+	for _, ff := range s.shards {
+		ff.OnExpire(f)
+	}
+}
+
+// OnDeadLetter sets the callback receiving failed batches on every shard
+func (s *Sharded[T]) OnDeadLetter(f func(objs []T, info BatchInfo, err error)) {
+	for _, ff := range s.shards {
+		ff.OnDeadLetter(f)
+	}
+}
+
+// Ping postpones the timeout of every shard
+func (s *Sharded[T]) Ping() {
+	for _, ff := range s.shards {
+		ff.Ping()
+	}
+}
+
+// Purge clears the buffer of every shard
+func (s *Sharded[T]) Purge() error {
+	return s.each((*FlashFlood[T]).Purge)
+}
+
+// Peek returns up to n elements of the shards without removing them, shard after shard
+func (s *Sharded[T]) Peek(n int) []T {
+	var objs []T
+	for _, ff := range s.shards {
+		if len(objs) >= n {
+			break
+		}
+		objs = append(objs, ff.Peek(n-len(objs))...)
+	}
+	return objs
+}
+
+// Snapshot returns a copy of the elements of all shards, shard after shard
+func (s *Sharded[T]) Snapshot() []T {
+	var objs []T
+	for _, ff := range s.shards {
+		objs = append(objs, ff.Snapshot()...)
+	}
+	return objs
+}
+
+// Range calls f for the elements of all shards, shard after shard, until f returns false. See FlashFlood.Range
+func (s *Sharded[T]) Range(f func(k int, obj T) bool) {
+	n, more := 0, true
+	for _, ff := range s.shards {
+		ff.Range(func(_ int, obj T) bool {
+			more = f(n, obj)
+			n++
+			return more
+		})
+		if !more {
+			return
+		}
+	}
+}
+
+// RemoveFunc removes the elements pred returns true for from every shard, see FlashFlood.RemoveFunc
+func (s *Sharded[T]) RemoveFunc(pred func(obj T) bool) int {
+	removed := 0
+	for _, ff := range s.shards {
+		removed += ff.RemoveFunc(pred)
+	}
+	return removed
+}
+
+// RetainFunc keeps only the elements keep returns true for in every shard, see FlashFlood.RetainFunc
+func (s *Sharded[T]) RetainFunc(keep func(obj T) bool) int {
+	removed := 0
+	for _, ff := range s.shards {
+		removed += ff.RetainFunc(keep)
+	}
+	return removed
+}
+
+// Replace replaces the elements pred returns true for with obj in every shard, see FlashFlood.Replace
+func (s *Sharded[T]) Replace(pred func(obj T) bool, obj T) int {
+	replaced := 0
+	for _, ff := range s.shards {
+		replaced += ff.Replace(pred, obj)
+	}
+	return replaced
+}
+
+// UpdateFunc replaces every element of every shard by the result of update, see FlashFlood.UpdateFunc
+func (s *Sharded[T]) UpdateFunc(update func(obj T) T) {
+	for _, ff := range s.shards {
+		ff.UpdateFunc(update)
+	}
+}
+
+// Pause stops flushing on every shard, see FlashFlood.Pause
+func (s *Sharded[T]) Pause() {
+	for _, ff := range s.shards {
+		ff.Pause()
+	}
+}
+
+// Resume starts flushing again on every shard, see FlashFlood.Resume
+func (s *Sharded[T]) Resume() {
+	for _, ff := range s.shards {
+		ff.Resume()
+	}
+}
+
+// State returns the state of the shards, they are paused and closed together
+func (s *Sharded[T]) State() State {
+	return s.shards[0].State()
+}
+
+// Count returns amount of elements in all shards
+func (s *Sharded[T]) Count() uint64 {
+	var cnt uint64
+	for _, ff := range s.shards {
+		cnt += ff.Count()
+	}
+	return cnt
+}
+
+// Stats returns the counters of all shards added up
+func (s *Sharded[T]) Stats() Stats {
+	var st Stats
+	for _, ff := range s.shards {
+		st = st.add(ff.Stats())
+	}
+	return st
+}
+
+// Close closes every shard, elements left in the buffers are dropped (see Shutdown)
+func (s *Sharded[T]) Close() {
+	closed := false
+	for _, ff := range s.shards {
+		if ff.close() {
+			closed = true
+		}
+	}
+	if closed {
+		close(s.shards[0].errs)
+	}
+}
+
+// Shutdown flushes the remaining buffer of every shard and closes the shared channel once all shards are done
+func (s *Sharded[T]) Shutdown(ctx context.Context) error {
+	var err error
+	closed := 0
+	for _, ff := range s.shards {
+		e := ff.shutdown(ctx, ReasonShutdown)
+		if e == ErrClosed {
+			closed++
+			continue
+		}
+		if e != nil && err == nil {
+			err = e
+		}
+	}
+	if closed == len(s.shards) {
+		return ErrClosed
+	}
+	s.shards[0].closeOutput()
+	return err
+}
@@ -0,0 +1,141 @@
+package flashflood
+
+import (
+	"time"
+)
+
+// handleTimer is used when no Scheduler is set, it drains the buffer once Timeout or FlushTimeout is due. The timer is only armed while the buffer holds
+// (or holds back) elements, so idle instances cost nothing
+func handleTimer[T any](i *FlashFlood[T]) {
+	defer i.timerWg.Done()
+
+	timer := time.NewTimer(0)
+	stopTimer(timer)
+
+	for {
+		var fire <-chan time.Time
+		if wait, ok := i.nextWake(time.Now()); ok {
+			timer.Reset(wait)
+			fire = timer.C
+		}
+
+		select {
+		case <-i.timerCtx.Done():
+			stopTimer(timer)
+			return
+		case <-i.wake:
+			stopTimer(timer)
+		case now := <-fire:
+			i.onWake(now)
+		}
+	}
+}
+
+// nextWake returns the time until the flush policy wants to be consulted, false when there is nothing to wait for
+func (i *FlashFlood[T]) nextWake(now time.Time) (time.Duration, bool) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	if i.closed.Load() {
+		return 0, false
+	}
+
+	var wait time.Duration
+	ok := false
+	i.parked = false
+	if i.buffer.len() > 0 && !i.paused {
+		wait, ok = i.policy.Wait(i.bufferStats(now))
+		// a policy that is due but did not release waits for a push, instead of waking over and over
+		if ok && wait <= 0 && i.stalled {
+			ok = false
+		}
+		i.parked = !ok
+		// a retained batch is not retried before retryAt
+		if retry := time.Duration(i.retryAt - now.UnixNano()); retry > 0 && (!ok || wait < retry) {
+			wait, ok = retry, true
+		}
+	}
+	// expired elements are evicted even when nothing is released
+	if until := time.Duration(i.nextExpiry - now.UnixNano()); i.buffer.len() > 0 && i.nextExpiry > 0 && (!ok || until < wait) {
+		wait, ok = until, true
+	}
+	// held back elements are pushed once due
+	if i.delayed.len() > 0 {
+		if until := time.Duration(i.delayed.next() - now.UnixNano()); !ok || until < wait {
+			wait, ok = until, true
+		}
+	}
+	return wait, ok
+}
+
+// onWake consults the flush policy, Ping may have postponed the timeout since the timer was armed
+func (i *FlashFlood[T]) onWake(now time.Time) {
+	i.mutex.Lock()
+	defer i.unlock()
+	if i.closed.Load() {
+		return
+	}
+	i.promote(now)
+	i.stalled = false
+	if i.buffer.len() == 0 {
+		return
+	}
+
+	i.expire(now)
+	before := i.buffer.len()
+	wait, due := i.policy.Wait(i.bufferStats(now))
+	i.release(now, false)
+	// only a policy that was due at now and still released nothing gives up the timer, a policy that is not due yet
+	// (a Ping or push came in before the wake) keeps it
+	i.stalled = due && wait <= 0 && i.buffer.len() == before && !i.paused && now.UnixNano() >= i.retryAt
+	if i.buffer.len() < before {
+		i.lastFlush.Store(now.UnixNano())
+		if i.buffer.len() == 0 {
+			i.clearBuffer()
+		}
+	}
+}
+
+// armTimer wakes up the timer goroutine when the first element enters the buffer, make sure we have a mutex Lock
+func (i *FlashFlood[T]) armTimer() {
+	if i.buffer.len() != 0 {
+		return
+	}
+
+	// the flush timeout of an empty buffer starts counting at its first element
+	i.lastFlush.Store(time.Now().UnixNano())
+	i.wakeTimer()
+}
+
+// wakeTimer makes the timer goroutine or the Scheduler consult nextWake again, make sure we have a mutex Lock
+func (i *FlashFlood[T]) wakeTimer() {
+	if i.opts.Scheduler != nil {
+		i.opts.Scheduler.reschedule(i)
+		return
+	}
+
+	select {
+	case i.wake <- struct{}{}:
+	default:
+	}
+}
+
+// haltTimer stops the timer goroutine or unregisters from the Scheduler
+func (i *FlashFlood[T]) haltTimer() {
+	if i.opts.Scheduler != nil {
+		i.opts.Scheduler.unregister(i)
+		return
+	}
+
+	(*i.timerCancel)()
+	i.timerWg.Wait()
+}
+
+func stopTimer(t *time.Timer) {
+	if !t.Stop() {
+		select {
+		case <-t.C:
+		default:
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package flashflood
+
+import (
+	"testing"
+	"time"
+)
+
+// TestWakeNotDue a wake the policy was not due for yet must not give up the timer, the late wait is armed again
+func TestWakeNotDue(t *testing.T) {
+	ff := New[int](&Opts{Timeout: 500 * time.Millisecond})
+	defer ff.Close()
+	_, _ = ff.GetChan()
+
+	_ = ff.Push(1)
+	now := time.Now()
+	// the Ping lands past the time the wake runs for
+	ff.lastAction.Store(now.Add(time.Millisecond).UnixNano())
+	ff.onWake(now)
+
+	if wait, ok := ff.nextWake(now.Add(time.Second)); !ok || wait > 0 {
+		t.Fatalf("expected: a due wake; got %v %v", wait, ok)
+	}
+	if ff.Count() != 1 {
+		t.Fatalf("expected 1 in buffer; got %v", ff.Count())
+	}
+}
@@ -0,0 +1,62 @@
+package flashflood
+
+import (
+	"context"
+	"time"
+)
+
+// OnExpire sets a callback called for every element evicted from the buffer because its TTL passed (see
+// Opts.ElementTTL and PushWithTTL). f is called while the buffer is locked, so it must not call methods of the instance
+func (i *FlashFlood[T]) OnExpire(f func(obj T)) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	i.onExpire = f
+}
+
+// PushWithTTL add objects to buffer, they are discarded instead of delivered once they were buffered for ttl. Blocks
+// while the buffer is at MaxBufferAmount
+func (i *FlashFlood[T]) PushWithTTL(ttl time.Duration, objs ...T) error {
+	return i.PushWithTTLContext(context.Background(), ttl, objs...)
+}
+
+// PushWithTTLContext add objects to buffer with a TTL, blocks until there is room below MaxBufferAmount or ctx is done
+func (i *FlashFlood[T]) PushWithTTLContext(ctx context.Context, ttl time.Duration, objs ...T) error {
+	return i.pushContext(ctx, placement{ttl: ttl}, objs)
+}
+
+// expireAt sets the time e expires at (unix nano), make sure we have a mutex Lock
+func (i *FlashFlood[T]) expireAt(e *entry[T], at int64) {
+	e.expires = at
+	if i.nextExpiry == 0 || at < i.nextExpiry {
+		i.nextExpiry = at
+	}
+}
+
+// expire evicts the elements whose TTL passed at now, the buffer is only searched once the earliest expiry is due.
+// Make sure we have a mutex Lock
+func (i *FlashFlood[T]) expire(now time.Time) {
+	if i.nextExpiry == 0 || now.UnixNano() < i.nextExpiry {
+		return
+	}
+
+	next := int64(0)
+	i.buffer.filter(func(e *entry[T]) bool {
+		if e.expires == 0 {
+			return true
+		}
+		if e.expires <= now.UnixNano() {
+			i.bytes -= e.size
+			i.stats.expired.Add(1)
+			if i.onExpire != nil {
+				i.onExpire(e.value)
+			}
+			return false
+		}
+		if next == 0 || e.expires < next {
+			next = e.expires
+		}
+		return true
+	})
+	i.nextExpiry = next
+	i.signalSpace()
+}
@@ -3,34 +3,88 @@ package flashflood
 import (
 	"context"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // FuncStack type function to be called as callback on drained elements
 type FuncStack[T any] func(objs []T, ff *FlashFlood[T]) []T
 
+// stackFunc the form every FuncStack variant is stored in
+type stackFunc[T any] func(objs []T, info BatchInfo, ff *FlashFlood[T]) ([]T, error)
+
+// entry an element in the buffer
+type entry[T any] struct {
+	value T
+	// unix nano time the element was pushed
+	pushed int64
+	// size of the element according to the sizer
+	size int
+	// unix nano time the element expires, 0 is never (see Opts.ElementTTL)
+	expires int64
+}
+
 // FlashFlood struct with generic type parameter
 type FlashFlood[T any] struct {
-	buffer       []T
+	buffer  queue[T]
+	scratch []T
+	// elements held back until they are due, see PushAt
+	delayed      delayedHeap[T]
+	bytes        int
+	sizer        func(obj T) int
+	policy       FlushPolicy
 	bufferAmount int64
 	mutex        *sync.Mutex
+	spaceFreed   chan struct{}
 
-	tickerCtx    context.Context
-	tickerCancel *context.CancelFunc
-	ticker       *time.Ticker
-	tickerWg     *sync.WaitGroup
+	timerCtx    context.Context
+	timerCancel *context.CancelFunc
+	timerWg     *sync.WaitGroup
+	wake        chan struct{}
 
 	floodChan      chan T
+	batchChan      chan []T
+	batchOnce      *sync.Once
+	batchInfoChan  chan Batch[T]
+	batchInfoOnce  *sync.Once
+	batchSeq       *atomic.Uint64
+	output         *atomic.Int32
+	closed         *atomic.Bool
 	channelFetched *ChannelFetchedStatus
+	// amount of elements or batches that made it on the channel, see Flush
+	sent *atomic.Uint64
 
-	lastAction *sync.Map
-	lastFlush  *sync.Map
+	lastAction *atomic.Int64
+	lastFlush  *atomic.Int64
 
 	flushTimeout time.Duration
 	flushEnabled bool
 	timeout      time.Duration
 
-	funcstack  []FuncStack[T]
+	onDrop   func(obj T)
+	onExpire func(obj T)
+	// unix nano time the next element expires, 0 is never
+	nextExpiry   int64
+	onDeadLetter func(objs []T, info BatchInfo, err error)
+	errs         chan error
+	// errors waiting for Opts.OnError until the lock is released
+	reported []error
+	// unix nano time before which a retained batch is not released again
+	retryAt int64
+	// no flush policy releases while paused, see Pause
+	paused bool
+	// the last wake released nothing, a policy wait that passed is not armed again until a push (see nextWake)
+	stalled bool
+	// the timer has no policy wait armed for the buffered elements, a push wakes it
+	parked bool
+	stats  *stats
+
+	// nil unless AsyncDispatch is set
+	dispatcher *dispatcher[T]
+	// receives the batches with outputSink
+	sink func(ctx context.Context, objs []T, info BatchInfo) error
+
+	funcstack  []stackFunc[T]
 	gateAmount int64
 	debug      bool
 	opts       *Opts
@@ -39,16 +93,25 @@ type FlashFlood[T any] struct {
 // FF the generic interface
 type FF[T any] interface {
 	AddFunc(f FuncStack[T])
+	AddFuncInfo(f FuncStackInfo[T])
+	AddFuncE(f FuncStackE[T])
+	Errors() <-chan error
 	Close()
+	Shutdown(ctx context.Context) error
 	Count() uint64
+	Stats() Stats
 	Drain(onChannel bool, respectGate bool) ([]T, error)
 	GetChan() (<-chan T, error)
+	GetBatchChan() (<-chan []T, error)
+	GetBatchInfoChan() (<-chan Batch[T], error)
 	GetOnChan(amount int) error
 	Get(amount int) ([]T, error)
 	Unshift(objs ...T) error
 	Ping()
 	Purge() error
 	Push(objs ...T) error
+	PushContext(ctx context.Context, objs ...T) error
+	TryPush(objs ...T) error
 }
 
 // Opts ...
@@ -63,12 +126,112 @@ type Opts struct {
 	FlushTimeout time.Duration
 	// default time before the buffer times out and will start draining its contents to the channel
 	Timeout time.Duration
-	// default ticker time the buffer will check for activity (see Timeout)
+	// maximum time an element stays in the buffer since it was pushed, regardless of activity (0 is disabled)
+	MaxLatency time.Duration
+	// Deprecated: timeouts are deadline driven, TickerTime is ignored
 	TickerTime time.Duration
+	// hard cap of elements in the internal buffer, Push and PushContext block and TryPush returns ErrFull when reached (0 is unbounded)
+	MaxBufferAmount int64
 	// the amount the channel will buffer
 	ChannelBuffer uint64
 	// default gate amount, open up the gate is this amount of elements need to be drained. (useful in conjunction with callback functions)
 	GateAmount int64
+	// byte gate, release batches once they hold this amount of bytes (requires SetSizer, 0 is disabled)
+	GateBytes int
+	// cap of the bytes in a single batch, larger batches are split (requires SetSizer, 0 is disabled)
+	MaxBatchBytes int
 	// debug output of the drain handlers' current elements
 	Debug bool
+	// drive the timeouts from a shared Scheduler instead of a goroutine per instance
+	Scheduler *Scheduler
+	// decides when elements are released, replaces the BufferAmount, Timeout, FlushTimeout and MaxLatency behavior
+	// (see DefaultFlushPolicy). Released elements are still flushed per GateAmount
+	FlushPolicy FlushPolicy
+	// what to do when elements are flushed while the channel is full (default OverflowBlock)
+	OverflowPolicy OverflowPolicy
+	// time a flush blocks on a full channel before dropping the elements, used by OverflowBlockWithTimeout
+	OverflowTimeout time.Duration
+	// what to do with a batch when a FuncStackE function fails (default FailureRetain)
+	FailurePolicy FailurePolicy
+	// called with every error of a flush not triggered by a method call (timeouts and ring releases), see Errors. It is
+	// called once the buffer is unlocked, so it may call methods of the instance
+	OnError func(err error)
+	// run the FuncStack and channel sends on a dispatcher goroutine instead of under the lock of Push, Unshift and
+	// Drain. Batches keep their order, but are no longer on the channel when the call returns and their errors are
+	// reported on Errors. A batch retained by FailureRetain takes the batches queued behind it back into the buffer
+	AsyncDispatch bool
+	// amount of priority levels for PushPriority, level 0 is the level of Push (0 and 1 disable priorities)
+	PriorityLevels int
+	// elements a priority level releases per turn, indexed by level. The levels take turns from the highest to the
+	// lowest, so lower levels are not starved. Without weights the highest level holding elements always releases first
+	PriorityWeights []int
+	// order the buffer by deadline and release the earliest deadline first, elements are flushed once due regardless of
+	// the gate. The deadline is set by PushDeadline or SetDeadlineFunc and defaults to the push time plus Timeout, the
+	// idle Timeout of the whole buffer no longer applies. Replaces PriorityLevels and Fairness
+	DeadlineOrdered bool
+	// flush elements this long before their deadline, with DeadlineOrdered
+	DeadlineSlack time.Duration
+	// time after which a pushed element is discarded instead of delivered, evicted elements are handed to OnExpire
+	// (0 is never, see PushWithTTL)
+	ElementTTL time.Duration
+	// order of the elements of different keys leaving the buffer, the key is set with SetFairnessKey (default
+	// FairnessNone)
+	Fairness Fairness
+	// maximum amount of elements of a single key in the buffer with Fairness, Push and PushContext block and TryPush
+	// returns ErrFull when reached (0 is unbounded)
+	KeyQuota int64
+	// maximum amount of keys buffering at the same time, Keyed returns ErrMaxKeys for new keys beyond it (0 is unlimited).
+	// Keys without elements do not count
+	MaxKeys int
+	// time without activity after which Keyed flushes and removes the buffer of a key (0 is never)
+	KeyIdleTimeout time.Duration
+}
+
+// Stats counters of an instance
+type Stats struct {
+	// amount of elements in the buffer
+	Buffered uint64
+	// amount of elements dropped by the overflow policy
+	Dropped uint64
+	// amount of batches the FuncStack failed on, a FuncStackE function returning an error or a FuncStack function
+	// panicking
+	Failed uint64
+	// amount of panics recovered from FuncStack functions, the batches count towards Failed as well
+	Panics uint64
+	// amount of elements held back until they are due (see PushAt)
+	Delayed uint64
+	// amount of elements evicted because their TTL passed (see Opts.ElementTTL)
+	Expired uint64
+	// amount of elements cut from the buffer waiting for the dispatcher (see Opts.AsyncDispatch)
+	Queued uint64
+	// counters per priority level, nil without Opts.PriorityLevels
+	Priority []PriorityStats
+}
+
+func (s Stats) add(o Stats) Stats {
+	s.Buffered += o.Buffered
+	s.Dropped += o.Dropped
+	s.Failed += o.Failed
+	s.Panics += o.Panics
+	s.Expired += o.Expired
+	s.Delayed += o.Delayed
+	s.Queued += o.Queued
+	if len(o.Priority) > 0 {
+		priority := make([]PriorityStats, max(len(s.Priority), len(o.Priority)))
+		copy(priority, s.Priority)
+		for level, p := range o.Priority {
+			priority[level].Buffered += p.Buffered
+			priority[level].Pushed += p.Pushed
+			priority[level].Released += p.Released
+		}
+		s.Priority = priority
+	}
+	return s
+}
+
+type stats struct {
+	dropped atomic.Uint64
+	failed  atomic.Uint64
+	panics  atomic.Uint64
+	expired atomic.Uint64
 }